@@ -2,36 +2,128 @@ package main
 
 import (
 	"bytes"
+	"flag"
 	"fmt"
 	"go/ast"
+	"go/build/constraint"
 	"go/parser"
 	"go/printer"
+	"go/scanner"
 	"go/token"
 	"log"
 	"math/rand"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 )
 
+var (
+	dirFlag  = flag.String("dir", "", "process every Go file of the package in `dir`")
+	tagsFlag = flag.String("tags", "", "comma-separated list of build `tags` used to select files")
+
+	checkFlag = flag.Bool("check", false,
+		"don't write files; print a diff of out of date files and exit non-zero")
+	dumpFlag = flag.Bool("dump", false,
+		"if the generated code is invalid, write it unformatted to `file.go.broken`")
+	cleanFlag = flag.Bool("clean", false,
+		"remove generated files whose source is gone or has no descriptors")
+
+	outFlag    = flag.String("o", "", "write the output of the single source file to `file`")
+	suffixFlag = flag.String("suffix", "",
+		"name output files `name_suffix.go` instead of prefixing \""+generatedPrefix+"\"")
+)
+
 func main() {
 	log.SetFlags(0)
 	log.SetPrefix("golific: ")
 
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: golific [flags] [file.go | dir | dir/...]...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
 	rand.Seed(time.Now().UnixNano())
 
-	var data = FileData{
-		Imports: make(map[string]bool, 3),
+	var paths = flag.Args()
+	if *dirFlag != "" {
+		paths = append(paths, *dirFlag)
+	}
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	var tags []string
+	for _, tag := range strings.Split(*tagsFlag, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	files, err := expandPaths(paths, tags)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *outFlag != "" && len(files) != 1 {
+		log.Fatalf("-o needs exactly one source file; found %d", len(files))
 	}
 
-	for _, filePath := range os.Args[1:] {
-		fmt.Printf("Processing file: %q\n", filePath)
+	var diagnostics []*Diagnostic
+	var notes []string
+	var stale int
+	var outputs = make(map[string]string, len(files)) // By source file
+
+	for _, filePath := range files {
+		if !*checkFlag {
+			fmt.Printf("Processing file: %q\n", filePath)
+		}
+
+		// Each file gets its own data, so nothing leaks into the output of another
+		var data = FileData{
+			Imports: make(map[string]bool, 3),
+		}
 
 		if err := data.DoFile(filePath); err != nil {
-			fmt.Printf("File not generated; error: %s\n", err.Error())
+			data.report(token.Position{Filename: filePath}, err)
+		}
+
+		diagnostics = append(diagnostics, data.Diagnostics...)
+		notes = append(notes, data.Notes...)
+
+		if len(data.Diagnostics) == 0 && data.File != "" {
+			outputs[filepath.Clean(filePath)] = data.File
+		}
+
+		if data.Stale {
+			stale++
+		}
+	}
+
+	// Every diagnostic of the run is printed before failing, so that all the
+	// problems can be fixed at once.
+	for _, d := range diagnostics {
+		fmt.Fprintln(os.Stderr, d)
+	}
+	for _, note := range notes {
+		fmt.Fprintln(os.Stderr, "note:", note)
+	}
+
+	if *cleanFlag && !*checkFlag {
+		if err := removeOrphans(files, outputs); err != nil {
+			log.Fatal(err)
 		}
 	}
+
+	if n := len(diagnostics); n > 0 {
+		log.Fatalf("%d error(s) found", n)
+	}
+
+	if stale > 0 {
+		log.Fatalf("%d generated file(s) out of date; run golific", stale)
+	}
 }
 
 var b bytes.Buffer
@@ -49,12 +141,38 @@ func typeString(fset *token.FileSet, node interface{}) (string, error) {
 type FileData struct {
 	fset    *token.FileSet
 	Package string
-	Name    string
-	File    string
-	Enums   []*EnumRepr
-	Structs []*StructRepr
-	Unions  []*UnionRepr
-	Imports map[string]bool
+	PkgPath string // Import path of the package, used to derive unique ids
+	// The `//go:build` line of the source, which the output must share
+	BuildConstraint string
+	Name            string
+	File            string
+	Source          string // Path of the source file, relative to File
+	Enums           []*EnumRepr
+	Structs         []*StructRepr
+	Unions          []*UnionRepr
+	Imports         map[string]bool
+
+	// The imports of the source by the name they're used with, so that those
+	// used by the types of variants can be added to the output.
+	srcImports map[string]*ast.ImportSpec
+	// The names given in the source to the imports of the output that it renamed
+	importNames map[string]string
+
+	// The defaults start as those of the config, and are changed by the
+	// -defaults descriptors of the file. They never carry over to other files.
+	config         *Config
+	enumDefaults   EnumDefaults
+	structDefaults StructDefaults
+	unionDefaults  UnionDefaults
+
+	Diagnostics []*Diagnostic // All errors found in the file
+	Notes       []string      // Information about the errors, which isn't counted
+	Stale       bool          // With -check, the file on disk is out of date
+}
+
+// Records the error, using `pos` for any part of it that has no position.
+func (self *FileData) report(pos token.Position, err error) {
+	self.Diagnostics = append(self.Diagnostics, diagnostics(positionErr(pos, err))...)
 }
 
 func (self *FileData) DoFile(filePath string) error {
@@ -62,18 +180,74 @@ func (self *FileData) DoFile(filePath string) error {
 
 	f, err := parser.ParseFile(self.fset, filePath, nil, parser.ParseComments)
 	if err != nil {
+		if list, ok := err.(scanner.ErrorList); ok {
+			for _, e := range list {
+				self.Diagnostics = append(self.Diagnostics, &Diagnostic{Pos: e.Pos, Msg: e.Msg})
+			}
+			return nil
+		}
 		return err
 	}
 
+	if ast.IsGenerated(f) {
+		return nil // Never look for descriptors in generated code
+	}
+
 	self.Package = f.Name.Name
+	self.BuildConstraint = ""
+
+	for _, cg := range f.Comments {
+		if cg.Pos() > f.Package {
+			break
+		}
+		for _, c := range cg.List {
+			if constraint.IsGoBuild(c.Text) {
+				self.BuildConstraint = c.Text
+			}
+		}
+	}
+
+	self.srcImports = make(map[string]*ast.ImportSpec, len(f.Imports))
+
+	for _, imp := range f.Imports {
+		var path, _ = strconv.Unquote(imp.Path.Value)
+
+		if imp.Name == nil {
+			self.srcImports[importName(path)] = imp
+		} else if imp.Name.Name != "_" && imp.Name.Name != "." {
+			self.srcImports[imp.Name.Name] = imp
+		}
+	}
 
 	var dir, filename = filepath.Split(filePath)
 
+	self.PkgPath = packagePath(dir, self.Package)
+
+	if self.config, err = loadConfig(dir); err != nil {
+		return err
+	}
+
+	self.enumDefaults = self.config.enumDefaults
+	self.structDefaults = self.config.structDefaults
+	self.unionDefaults = self.config.unionDefaults
+
 	self.Name = filename
-	self.File = filepath.Join(dir, "golific____"+filename)
+	self.File = outputName(dir, filename)
+
+	// The source is named relative to the output, so that -clean can find it
+	if self.Source, err = filepath.Rel(filepath.Dir(self.File), filePath); err != nil {
+		return err
+	}
+	self.Source = filepath.ToSlash(self.Source)
 
 	ast.Walk(self, f)
 
+	// The output would lack the descriptors that failed, so the previous one
+	// is left in place until the errors are fixed.
+	if len(self.Diagnostics) != 0 {
+		return nil
+	}
+
 	if err := self.generateCode(); err != nil {
 		return err
 	}
@@ -81,6 +255,61 @@ func (self *FileData) DoFile(filePath string) error {
 	return nil
 }
 
+/*
+Returns the name a package is usually referred to by when its import isn't
+renamed, which is the last element of its path, without any major version
+suffix, `.vN` suffix or `go-` prefix.
+*/
+func importName(path string) string {
+	var parts = strings.Split(path, "/")
+	var name = parts[len(parts)-1]
+
+	if len(parts) > 1 && len(name) > 1 && name[0] == 'v' &&
+		strings.Trim(name[1:], "0123456789") == "" {
+		name = parts[len(parts)-2]
+	}
+
+	if idx := strings.Index(name, ".v"); idx > 0 {
+		name = name[:idx]
+	}
+	return strings.TrimPrefix(name, "go-")
+}
+
+// Adds the imports of the source used by the type, since the generated code
+// writes the type as it's written in the source.
+func (self *FileData) addTypeImports(typ ast.Expr) {
+	ast.Inspect(typ, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+
+		if id, ok := sel.X.(*ast.Ident); ok {
+			if imp, ok := self.srcImports[id.Name]; ok {
+				var path, _ = strconv.Unquote(imp.Path.Value)
+				self.Imports[path] = true
+
+				if imp.Name != nil {
+					if self.importNames == nil {
+						self.importNames = make(map[string]string)
+					}
+					self.importNames[path] = imp.Name.Name
+				}
+			}
+		}
+		return false
+	})
+}
+
+// ImportSpec returns the import of the path for the output, renamed if the
+// source renamed it.
+func (self *FileData) ImportSpec(path string) string {
+	if name, ok := self.importNames[path]; ok {
+		return name + " " + strconv.Quote(path)
+	}
+	return strconv.Quote(path)
+}
+
 /*
 Returns the proper function to process an annotation, if found.
 */
@@ -111,7 +340,7 @@ func (self *FileData) tryDecl(cList []*ast.Comment, spec *ast.TypeSpec) {
 	}
 
 	var err error
-	var name, prefix string
+	var prefix string
 
 	if prefix = getPrefix(cgText); prefix == "" {
 		return
@@ -125,8 +354,6 @@ func (self *FileData) tryDecl(cList []*ast.Comment, spec *ast.TypeSpec) {
 	if err == nil {
 		cgText = strings.TrimSpace(cgText[len(prefix):]) // Strip away the prefix
 
-		log.SetPrefix(fmt.Sprintf("golific-%s (%s): ", prefix, self.Name))
-
 		switch prefix {
 		case "@enum":
 			err = self.newEnum(self.fset, cgText, cList[1:], spec, strct)
@@ -152,11 +379,7 @@ func (self *FileData) tryDecl(cList []*ast.Comment, spec *ast.TypeSpec) {
 	}
 
 	if err != nil {
-		if len(name) > 0 {
-			log.Printf("%s: %s\n", name, err)
-		} else {
-			log.Println(err)
-		}
+		self.report(self.fset.Position(spec.Pos()), err)
 	}
 }
 
@@ -0,0 +1,15 @@
+/*
+The exhaustive command runs the exhaustive analyzer. It may be run directly,
+or used with `go vet -vettool=$(which exhaustive)`.
+*/
+package main
+
+import (
+	"Golific/analysis/exhaustive"
+
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(exhaustive.Analyzer)
+}
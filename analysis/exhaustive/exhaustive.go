@@ -0,0 +1,171 @@
+/*
+Package exhaustive defines an Analyzer that reports `switch` statements on
+Golific enums that neither handle every variant nor have a `default` case.
+
+An enum is recognized by its generated `XxxEnum` type, which is a struct holding
+an unexported `value_...` field. Its variants are the fields of the generated
+`Xxx` namespace variable (the same fields as the `__Xxx` descriptor, plus the
+`Values` array), so the enums of any package can be checked using only its type
+information, without running Golific again.
+
+Bitflag enums are not checked, since their values may combine many variants.
+A switch is also not checked if any of its cases is something other than a
+direct reference to a variant, like `Animal.Dog`.
+*/
+package exhaustive
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "exhaustive",
+	Doc:      "check for switch statements that miss variants of a Golific enum",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	inspect.Preorder([]ast.Node{(*ast.SwitchStmt)(nil)}, func(n ast.Node) {
+		sw := n.(*ast.SwitchStmt)
+		if sw.Tag == nil {
+			return
+		}
+
+		enum := enumOf(pass.TypesInfo.TypeOf(sw.Tag))
+		if enum == nil {
+			return
+		}
+
+		var found = make(map[string]bool, len(enum.variants))
+
+		for _, stmt := range sw.Body.List {
+			clause := stmt.(*ast.CaseClause)
+			if clause.List == nil {
+				return // has a default case
+			}
+
+			for _, expr := range clause.List {
+				name, ok := enum.variantOf(pass.TypesInfo, expr)
+				if !ok {
+					return // not every case is known
+				}
+				found[name] = true
+			}
+		}
+
+		var missing []string
+		for _, name := range enum.variants {
+			if !found[name] {
+				missing = append(missing, enum.namespace+"."+name)
+			}
+		}
+
+		if len(missing) > 0 {
+			pass.Reportf(sw.Pos(), "missing cases in switch of type %s: %s",
+				enum.typeName, strings.Join(missing, ", "))
+		}
+	})
+
+	return nil, nil
+}
+
+type enumInfo struct {
+	typeName  string
+	namespace string
+	nsObj     types.Object // The namespace variable
+	variants  []string
+}
+
+// Returns the information of the Golific enum of the given type, or `nil` if
+// the type is not a (non-bitflag) Golific enum.
+func enumOf(typ types.Type) *enumInfo {
+	named, ok := types.Unalias(typ).(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return nil
+	}
+
+	var typeName = named.Obj().Name()
+	if !strings.HasSuffix(typeName, "Enum") || !isEnumStruct(named) {
+		return nil
+	}
+
+	// Bitflag enums are the only ones with an AddAll method
+	if obj, _, _ := types.LookupFieldOrMethod(named, false, named.Obj().Pkg(),
+		"AddAll"); obj != nil {
+		return nil
+	}
+
+	var info = enumInfo{
+		typeName:  typeName,
+		namespace: strings.TrimSuffix(typeName, "Enum"),
+	}
+
+	v, ok := named.Obj().Pkg().Scope().Lookup(info.namespace).(*types.Var)
+	if !ok {
+		return nil
+	}
+
+	if s, ok := v.Type().Underlying().(*types.Struct); ok {
+		info.nsObj = v
+
+		for i := 0; i < s.NumFields(); i++ { // Skips the array of all variants
+			if types.Identical(s.Field(i).Type(), named) {
+				info.variants = append(info.variants, s.Field(i).Name())
+			}
+		}
+	}
+
+	if len(info.variants) == 0 {
+		return nil
+	}
+	return &info
+}
+
+// Reports whether the type has the layout of an enum generated by Golific.
+func isEnumStruct(named *types.Named) bool {
+	s, ok := named.Underlying().(*types.Struct)
+	if !ok || s.NumFields() == 0 {
+		return false
+	}
+
+	for i := 0; i < s.NumFields(); i++ {
+		if s.Field(i).Exported() {
+			return false
+		}
+	}
+	return strings.HasPrefix(s.Field(0).Name(), "value_")
+}
+
+// Returns the name of the variant referenced by `expr`, like `Animal.Dog` or
+// `pkg.Animal.Dog`, and `false` if `expr` is not a reference to a variant.
+func (self *enumInfo) variantOf(info *types.Info, expr ast.Expr) (string, bool) {
+	sel, ok := ast.Unparen(expr).(*ast.SelectorExpr)
+	if !ok {
+		return "", false
+	}
+
+	var nsIdent *ast.Ident
+
+	switch x := ast.Unparen(sel.X).(type) {
+	case *ast.Ident:
+		nsIdent = x
+	case *ast.SelectorExpr:
+		nsIdent = x.Sel
+	default:
+		return "", false
+	}
+
+	if info.Uses[nsIdent] != self.nsObj {
+		return "", false
+	}
+	return sel.Sel.Name, true
+}
@@ -0,0 +1,13 @@
+package exhaustive_test
+
+import (
+	"testing"
+
+	"Golific/analysis/exhaustive"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), exhaustive.Analyzer, "a")
+}
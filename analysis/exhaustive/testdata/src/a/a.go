@@ -0,0 +1,61 @@
+package a
+
+import "enums"
+
+type Pet = enums.AnimalEnum
+
+func missing(a enums.AnimalEnum) {
+	switch a { // want `missing cases in switch of type AnimalEnum: Animal.Cat, Animal.Horse`
+	case enums.Animal.Dog:
+	}
+}
+
+func complete(a enums.AnimalEnum) {
+	switch a {
+	case enums.Animal.Dog, enums.Animal.Cat:
+	case (enums.Animal.Horse):
+	}
+}
+
+func withDefault(a enums.AnimalEnum) {
+	switch a {
+	case enums.Animal.Dog:
+	default:
+	}
+}
+
+func alias(p Pet) {
+	switch p { // want `missing cases in switch of type AnimalEnum: Animal.Dog`
+	case enums.Animal.Cat, enums.Animal.Horse:
+	}
+}
+
+func unknownCase(a, b enums.AnimalEnum) {
+	switch a {
+	case b:
+	}
+}
+
+func openMissing(c enums.ColorEnum) {
+	switch c { // want `missing cases in switch of type ColorEnum: Color.Blue`
+	case enums.Color.Red:
+	}
+}
+
+func openComplete(c enums.ColorEnum) {
+	switch c {
+	case enums.Color.Red, enums.Color.Blue:
+	}
+}
+
+func bitflag(p enums.PermEnum) {
+	switch p {
+	case enums.Perm.Read:
+	}
+}
+
+func notEnum(s enums.SizeEnum) {
+	switch s {
+	case enums.Size.Small:
+	}
+}
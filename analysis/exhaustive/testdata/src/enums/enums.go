@@ -0,0 +1,56 @@
+// Package enums holds hand-written copies of the types Golific generates, so
+// the analyzer can be tested without running the generator.
+package enums
+
+type AnimalEnum struct{ value_abc uint8 }
+
+var Animal = struct {
+	Dog   AnimalEnum
+	Cat   AnimalEnum
+	Horse AnimalEnum
+
+	Values [3]AnimalEnum
+}{
+	Dog:   AnimalEnum{1},
+	Cat:   AnimalEnum{2},
+	Horse: AnimalEnum{3},
+}
+
+// Open enums keep the JSON text of unknown values in a second field
+type ColorEnum struct {
+	value_def uint8
+	raw_def   string
+}
+
+var Color = struct {
+	Red  ColorEnum
+	Blue ColorEnum
+
+	Values [2]ColorEnum
+}{
+	Red:  ColorEnum{value_def: 1},
+	Blue: ColorEnum{value_def: 2},
+}
+
+// Bitflag enums are the only ones with an AddAll method
+type PermEnum struct{ value_ghi uint8 }
+
+func (self PermEnum) AddAll(p ...PermEnum) PermEnum { return self }
+
+var Perm = struct {
+	Read  PermEnum
+	Write PermEnum
+
+	Values [2]PermEnum
+}{
+	Read:  PermEnum{1},
+	Write: PermEnum{2},
+}
+
+// Not generated by Golific, since its field is exported
+type SizeEnum struct{ Value uint8 }
+
+var Size = struct {
+	Small SizeEnum
+	Large SizeEnum
+}{}
@@ -2,10 +2,14 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"go/ast"
 	"go/format"
+	"go/scanner"
 	"go/token"
+	"hash/fnv"
+	"io/fs"
 	"math/rand"
 	"os"
 	"strconv"
@@ -19,6 +23,7 @@ const _defaults = "-defaults"
 const (
 	// shared
 	dropJson = 1 << iota
+	randomId
 
 	// for enum
 	bitflags
@@ -27,17 +32,26 @@ const (
 	xmlMarshalIsString
 	xmlUnmarshalIsString
 	dropXml
+	textMarshalIsString
+	textUnmarshalIsString
+	dropText
+	doSql
+	sqlIsString
+	strict
+	open
 	hasDefault
 	hasCustomValue
+	bigValue // The value is a uint64 too large for int64
 
 	// for struct
 	embedded
 	hasJsonTag
 	jsonOmitEmpty
 	hasEmbeddedFields
-	hasPrivateJSON
-
-	privateJSON
+	dropUnmarshal
+	caseSensitive
+	disallowUnknownFields
+	jsonSkip
 )
 
 type Flag struct {
@@ -59,6 +73,20 @@ func getFlags(lit *ast.BasicLit) string {
 	return ""
 }
 
+// Reports whether the type is a predeclared boolean, string or numeric type,
+// which is never a struct or encoded as a JSON object.
+func isBasicType(typ ast.Expr) bool {
+	if id, ok := typ.(*ast.Ident); ok {
+		switch id.Name {
+		case "bool", "string",
+			"int", "int64", "int32", "int16", "int8", "uint", "uint64", "uint32",
+			"uint16", "uint8", "float64", "float32", "byte", "rune", "uintptr":
+			return true
+		}
+	}
+	return false
+}
+
 func isExportedIdent(id string) bool {
 	return len(id) > 0 && 'A' <= id[0] && id[0] <= 'Z'
 }
@@ -77,6 +105,18 @@ func (self *Flag) getNonEmpty() (string, error) {
 	return self.Value, nil
 }
 
+// Returns an id derived from the package path and the name, so that it is the
+// same on every run. If the `random_id` option is set, an empty string is
+// returned, and `getUniqueId` creates a random id instead.
+func (self *FileData) uniqueId(b *Base, name string) string {
+	if b.flags&randomId == randomId {
+		return ""
+	}
+	var h = fnv.New64a()
+	h.Write([]byte(self.PkgPath + "." + name))
+	return strconv.FormatUint(h.Sum64(), 36)
+}
+
 func (self *Base) getUniqueId() string {
 	if self.unique == "" {
 		self.unique = strconv.FormatInt(rand.Int63(), 36)
@@ -86,6 +126,7 @@ func (self *Base) getUniqueId() string {
 
 type Base struct {
 	fset   *token.FileSet
+	pos    token.Position // Position of the descriptor or field in the source
 	flags  uint
 	Tag    string // for processing flags, and for struct field tags
 	unique string
@@ -93,14 +134,75 @@ type Base struct {
 	docs   []string
 }
 
+// Diagnostic is an error found while processing a source file. It prints as
+// "file.go:12:3: message".
+type Diagnostic struct {
+	Pos token.Position
+	Msg string
+}
+
+func (self *Diagnostic) Error() string {
+	if !self.Pos.IsValid() && self.Pos.Filename == "" {
+		return self.Msg
+	}
+	return fmt.Sprintf("%s: %s", self.Pos, self.Msg)
+}
+
+// Returns a Diagnostic at the file:line:column of the descriptor or field.
+func (self *Base) errorf(format string, args ...interface{}) error {
+	return &Diagnostic{Pos: self.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Gives the error the position of the descriptor or field, unless it already
+// has one. Errors joined by errors.Join are positioned individually.
+func (self *Base) positioned(err error) error {
+	return positionErr(self.pos, err)
+}
+
+func positionErr(pos token.Position, err error) error {
+	switch e := err.(type) {
+	case nil, *Diagnostic:
+		return err
+	case interface{ Unwrap() []error }:
+		var errs = e.Unwrap()
+		for i := range errs {
+			errs[i] = positionErr(pos, errs[i])
+		}
+		return errors.Join(errs...)
+	case *fs.PathError:
+		if e.Path == pos.Filename { // Already named by the position
+			return &Diagnostic{Pos: pos, Msg: e.Err.Error()}
+		}
+	}
+	return &Diagnostic{Pos: pos, Msg: err.Error()}
+}
+
+// Flattens the error into the list of Diagnostics that it holds.
+func diagnostics(err error) (res []*Diagnostic) {
+	switch e := err.(type) {
+	case nil:
+	case *Diagnostic:
+		res = append(res, e)
+	case interface{ Unwrap() []error }:
+		for _, err := range e.Unwrap() {
+			res = append(res, diagnostics(err)...)
+		}
+	default:
+		res = append(res, &Diagnostic{Msg: err.Error()})
+	}
+	return res
+}
+
 func (self *Base) setDocsAndName(docs []*ast.Comment, spec *ast.TypeSpec, requirePfx bool) error {
+	self.pos = self.fset.Position(spec.Pos())
+
 	for _, d := range docs {
 		self.docs = append(self.docs, d.Text)
 	}
 
 	if self.Name = spec.Name.Name; requirePfx {
 		if !strings.HasPrefix(self.Name, "__") {
-			return fmt.Errorf("struct %q must start with '__'", self.Name)
+			return self.errorf("struct %q must start with '__'", self.Name)
 		}
 		self.Name = self.Name[2:] // slice away the '__'
 	}
@@ -140,6 +242,8 @@ type BaseFieldRepr struct {
 func (self *BaseFieldRepr) gatherCodeCommentsAndName(
 	f *ast.Field, allow_embedded bool) (err error) {
 
+	self.pos = self.fset.Position(f.Pos())
+
 	// Comes from any comment lines before a field
 	if f.Doc != nil {
 		for _, c := range f.Doc.List {
@@ -152,7 +256,7 @@ func (self *BaseFieldRepr) gatherCodeCommentsAndName(
 
 	} else if len(f.Names) != 1 {
 		// TODO: Need to support multiple names for a single definition
-		return fmt.Errorf("Struct field must have exactly one name")
+		return self.errorf("Struct field must have exactly one name")
 	}
 
 	if len(f.Names) >= 1 {
@@ -247,62 +351,176 @@ func (b *Base) genericGatherFlags(tagText string, fn func(Flag) error) (err erro
 }
 
 func (self *FileData) generateCode() error {
-	if len(self.Enums) == 0 && len(self.Structs) == 0 && len(self.Unions) == 0 {
+	b, err := self.render()
+	if err != nil {
+		return err
+	}
+
+	if *checkFlag {
+		return self.checkCode(b)
+	}
+
+	if b == nil {
+		if *cleanFlag {
+			return removeGenerated(self.File)
+		}
 		return nil
 	}
 
+	file, err := os.Create(self.File)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(b)
+	return err
+}
+
+// Compares the code with the file on disk, and prints the difference if it is
+// out of date. A missing file compares as empty.
+func (self *FileData) checkCode(b []byte) error {
+	old, err := os.ReadFile(self.File)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if diff := unifiedDiff(self.File, old, b); diff != "" {
+		fmt.Print(diff)
+		self.Stale = true
+	}
+	return nil
+}
+
+// Returns the formatted code for the file, or nil if it has no descriptors.
+func (self *FileData) render() ([]byte, error) {
+	if len(self.Enums) == 0 && len(self.Structs) == 0 && len(self.Unions) == 0 {
+		return nil, nil
+	}
+
 	self.GatherUnionImports()
 	self.GatherEnumImports()
 	self.GatherStructImports()
 
-	// Execute the template on the data gathered
+	// Execute the templates on the data gathered, one descriptor at a time, so
+	// that errors in the output can be traced back to where they came from.
 	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, self); err != nil {
-		return err
+	var chunks []chunk
+
+	var execute = func(name string, data interface{}, desc *Base) error {
+		chunks = append(chunks, chunk{
+			line: bytes.Count(buf.Bytes(), []byte{'\n'}) + 1, template: name, desc: desc,
+		})
+
+		if err := tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+			if desc != nil {
+				return desc.errorf("%s: %s", desc.Name, err)
+			}
+			return err
+		}
+		return nil
+	}
+
+	if err := execute("generate_golific", self, nil); err != nil {
+		return nil, err
+	}
+	for _, u := range self.Unions {
+		if err := execute("generate_union", []*UnionRepr{u}, &u.Base); err != nil {
+			return nil, err
+		}
+	}
+	for _, s := range self.Structs {
+		if err := execute("generate_struct", []*StructRepr{s}, &s.Base); err != nil {
+			return nil, err
+		}
+	}
+	for _, e := range self.Enums {
+		if err := execute("generate_enum", []*EnumRepr{e}, &e.Base); err != nil {
+			return nil, err
+		}
 	}
 
 	// Run the go code formatter to make sure syntax is correct before writing.
 	b, err := format.Source(buf.Bytes())
 	if err != nil {
-		b = buf.Bytes()
-		fmt.Println(string(buf.Bytes()))
-		//		return err
+		if *dumpFlag {
+			var dump = self.File + ".broken"
+			if err := os.WriteFile(dump, buf.Bytes(), 0666); err != nil {
+				return nil, err
+			}
+			self.Notes = append(self.Notes, "The unformatted output was written to "+dump)
+		}
+
+		return nil, traceFormatError(err, chunks)
 	}
 
-	file, err := os.Create(self.File)
-	if err != nil {
-		return err
+	return b, nil
+}
+
+// The part of the unformatted output that was generated by a template for a
+// single descriptor, or for the header if `desc` is nil.
+type chunk struct {
+	line     int // Line of the output on which the chunk starts
+	template string
+	desc     *Base
+}
+
+/*
+Gives each syntax error found by the formatter the position of the descriptor
+whose output contains it, along with the name of the template and the line of
+the error in that template's output. Only the first error of each descriptor
+is kept, since the rest usually follow from it.
+*/
+func traceFormatError(err error, chunks []chunk) error {
+	list, ok := err.(scanner.ErrorList)
+	if !ok {
+		return fmt.Errorf("Generated code could not be formatted: %s", err)
 	}
-	defer file.Close()
 
-	_, err = file.Write(b)
-	return err
+	var errs []error
+	var seen = make(map[int]bool)
+
+	for _, e := range list {
+		var idx = 0
+		for idx+1 < len(chunks) && chunks[idx+1].line <= e.Pos.Line {
+			idx++
+		}
+
+		if seen[idx] {
+			continue
+		}
+		seen[idx] = true
+
+		var c = chunks[idx]
+
+		var msg = fmt.Sprintf("Generated code is invalid at line %d of the output "+
+			"of template %q: %s", e.Pos.Line-c.line+1, c.template, e.Msg)
+
+		if c.desc != nil {
+			errs = append(errs, c.desc.errorf("%s: %s", c.desc.Name, msg))
+		} else {
+			errs = append(errs, errors.New(msg))
+		}
+	}
+
+	return errors.Join(errs...)
 }
 
 var tmpl = template.Must(template.New("generate_golific").Parse(
 	union_tmpl +
 		struct_tmpl +
 		enum_tmpl +
-		`/****************************************************************************
-	This file was generated by Golific.
+		generatedHeader + `
+// Source: {{.Source}}
 
-	Do not edit this file. If you do, your changes will be overwritten the next
-	time 'generate' is invoked.
-******************************************************************************/
+{{with .BuildConstraint}}{{.}}
 
+{{end -}}
 package {{.Package}}
 
 import (
   {{- range $imp, $_ := .Imports}}
-  {{printf "%q" $imp -}}
+  {{$.ImportSpec $imp -}}
   {{end -}}
 )
-
-
-{{- template "generate_union" .Unions}}
-
-{{- template "generate_struct" .Structs}}
-
-{{- template "generate_enum" .Enums}}
-
 `))
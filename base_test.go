@@ -0,0 +1,52 @@
+package main
+
+import (
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestPositionErrPathError(t *testing.T) {
+	_, err := os.Open("missing.go")
+
+	var d = positionErr(token.Position{Filename: "missing.go"}, err)
+	if expect := "missing.go: " + err.(*os.PathError).Err.Error(); d.Error() != expect {
+		t.Errorf("expected %q; found %q", expect, d)
+	}
+
+	// The path is kept if it isn't that of the position
+	d = positionErr(token.Position{Filename: "other.go"}, err)
+	if expect := "other.go: " + err.Error(); d.Error() != expect {
+		t.Errorf("expected %q; found %q", expect, d)
+	}
+}
+
+func TestDumpIsANote(t *testing.T) {
+	var oldTmpl, oldDump = tmpl, *dumpFlag
+	defer func() { tmpl, *dumpFlag = oldTmpl, oldDump }()
+
+	tmpl = template.Must(template.New("generate_golific").Parse(
+		`package p{{define "generate_enum"}} func ({{end}}`))
+	*dumpFlag = true
+
+	var data = FileData{
+		File:    filepath.Join(t.TempDir(), "out.go"),
+		Imports: make(map[string]bool),
+		Enums:   []*EnumRepr{{}},
+	}
+
+	_, err := data.render()
+	if n := len(diagnostics(err)); n != 1 {
+		t.Errorf("expected 1 error; found %d: %v", n, err)
+	}
+
+	if len(data.Notes) != 1 || !strings.Contains(data.Notes[0], data.File+".broken") {
+		t.Errorf("expected a note naming the dump; found %q", data.Notes)
+	}
+	if _, err := os.Stat(data.File + ".broken"); err != nil {
+		t.Error(err)
+	}
+}
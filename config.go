@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// The names of the config file, in the order they're looked for in a directory
+var configNames = [...]string{"golific.json", "golific.yaml", "golific.yml"}
+
+/*
+Config holds the defaults for every file of a module. It comes from the
+nearest golific.json or golific.yaml file, which gives the options for each
+kind of descriptor in the same syntax as the -defaults descriptors:
+
+	{
+	  "enum": "json:\"string\" strict",
+	  "union": "json_layout:\"adjacent\""
+	}
+
+or, in YAML:
+
+	enum: 'json:"string" strict'
+	union: 'json_layout:"adjacent"'
+*/
+type Config struct {
+	Enum   string `json:"enum"`
+	Struct string `json:"struct"`
+	Union  string `json:"union"`
+
+	enumDefaults   EnumDefaults
+	structDefaults StructDefaults
+	unionDefaults  UnionDefaults
+}
+
+// Configs already loaded, by directory
+var configs = make(map[string]*Config)
+
+/*
+Returns the config for files in `dir`. The config file is looked for in `dir`
+and each of its parents, up to the root of the module or repository. If none
+is found, the config has the built-in defaults.
+*/
+func loadConfig(dir string) (*Config, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg, ok := configs[abs]; ok {
+		return cfg, nil
+	}
+
+	var cfg *Config
+	var path = findConfig(abs)
+
+	if path == "" {
+		cfg = &Config{
+			enumDefaults:   enumDefaults,
+			structDefaults: structDefaults,
+			unionDefaults:  unionDefaults,
+		}
+	} else if cfg, err = readConfig(path); err != nil {
+		return nil, err
+	}
+
+	configs[abs] = cfg
+	return cfg, nil
+}
+
+// The files and directories that mark the root of a module or repository
+var rootMarkers = [...]string{"go.mod", ".git", ".hg", ".svn", ".bzr"}
+
+/*
+Returns the path of the nearest config file, or an empty string if none. The
+search stops at the root of the module or repository, so a config file outside
+of it is never used. If `dir` is in neither, only `dir` itself is searched.
+*/
+func findConfig(dir string) string {
+	var root = dir
+
+	for d := dir; ; d = filepath.Dir(d) {
+		if isRoot(d) {
+			root = d
+			break
+		}
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+
+	for {
+		for _, name := range configNames {
+			var path = filepath.Join(dir, name)
+			if _, err := os.Stat(path); err == nil {
+				return path
+			}
+		}
+
+		if dir == root {
+			return ""
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// Reports whether the directory is the root of a module or repository.
+func isRoot(dir string) bool {
+	for _, name := range rootMarkers {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func readConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg = Config{
+		enumDefaults:   enumDefaults,
+		structDefaults: structDefaults,
+		unionDefaults:  unionDefaults,
+	}
+
+	if filepath.Ext(path) == ".json" {
+		var dec = json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+
+		if err = dec.Decode(&cfg); err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+
+	} else if err = cfg.parseYaml(path, data); err != nil {
+		return nil, err
+	}
+
+	if err = cfg.enumDefaults.gatherFlags(cfg.Enum); err != nil {
+		return nil, fmt.Errorf("%s: enum: %s", path, err)
+	}
+	if err = cfg.structDefaults.gatherFlags(cfg.Struct); err != nil {
+		return nil, fmt.Errorf("%s: struct: %s", path, err)
+	}
+	if err = cfg.unionDefaults.gatherFlags(cfg.Union); err != nil {
+		return nil, fmt.Errorf("%s: union: %s", path, err)
+	}
+
+	return &cfg, nil
+}
+
+/*
+Reads the YAML form of the config. Only the subset needed is understood: one
+`key: value` per line, where the value may be single or double quoted. A `#`
+starts a comment if it begins the line or follows a space outside of quotes.
+*/
+func (self *Config) parseYaml(path string, data []byte) error {
+	for i, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line == "" || line[0] == '#' {
+			continue
+		}
+
+		var idx = strings.IndexByte(line, ':')
+		if idx == -1 {
+			return fmt.Errorf("%s:%d: Expected 'key: value'", path, i+1)
+		}
+
+		var key = strings.TrimSpace(line[:idx])
+
+		value, err := yamlValue(strings.TrimSpace(line[idx+1:]))
+		if err != nil {
+			return fmt.Errorf("%s:%d: %s", path, i+1, err)
+		}
+
+		switch key {
+		case "enum":
+			self.Enum = value
+		case "struct":
+			self.Struct = value
+		case "union":
+			self.Union = value
+		default:
+			return fmt.Errorf("%s:%d: Unknown key %q", path, i+1, key)
+		}
+	}
+	return nil
+}
+
+// Returns the value of a YAML scalar, which may be quoted and be followed by a
+// comment.
+func yamlValue(s string) (string, error) {
+	if s == "" || s[0] == '#' {
+		return "", nil
+	}
+
+	var value, rest string
+
+	switch s[0] {
+	case '\'':
+		var end = 1
+		for ; end < len(s); end++ {
+			if s[end] == '\'' {
+				if end+1 < len(s) && s[end+1] == '\'' {
+					end++ // An escaped quote
+					continue
+				}
+				break
+			}
+		}
+		if end == len(s) {
+			return "", fmt.Errorf("Unterminated quoted value")
+		}
+		value = strings.Replace(s[1:end], "''", "'", -1)
+		rest = s[end+1:]
+
+	case '"':
+		var end = 1
+		for ; end < len(s) && s[end] != '"'; end++ {
+			if s[end] == '\\' {
+				end++
+			}
+		}
+		if end >= len(s) {
+			return "", fmt.Errorf("Unterminated quoted value")
+		}
+
+		var err error
+		if value, err = strconv.Unquote(s[:end+1]); err != nil {
+			return "", fmt.Errorf("Invalid quoted value")
+		}
+		rest = s[end+1:]
+
+	default:
+		for i := 1; i < len(s); i++ {
+			if s[i] == '#' && (s[i-1] == ' ' || s[i-1] == '\t') {
+				return strings.TrimSpace(s[:i]), nil
+			}
+		}
+		return s, nil
+	}
+
+	if rest != "" { // Only a comment may follow, after a space
+		var comment = strings.TrimLeft(rest, " \t")
+		if comment == rest || comment[0] != '#' {
+			return "", fmt.Errorf("Unexpected text after the quoted value")
+		}
+	}
+	return value, nil
+}
+
+// Reports if the options of a -defaults descriptor start with `reset`, and
+// returns the options that follow it.
+func cutReset(tagText string) (string, bool) {
+	var fields = strings.Fields(tagText)
+
+	if len(fields) == 0 || fields[0] != "reset" {
+		return tagText, false
+	}
+	return strings.TrimSpace(strings.TrimSpace(tagText)[len("reset"):]), true
+}
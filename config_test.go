@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestYamlValue(t *testing.T) {
+	for _, test := range []struct {
+		input, expect string
+		ok            bool
+	}{
+		{`json:"string" strict`, `json:"string" strict`, true},
+		{`json:"string" strict # comment`, `json:"string" strict`, true},
+		{`strict	# comment`, `strict`, true},
+		{`a#b`, `a#b`, true},
+		{`# only a comment`, ``, true},
+		{``, ``, true},
+		{`'json:"string"' # comment`, `json:"string"`, true},
+		{`'it''s # not a comment'`, `it's # not a comment`, true},
+		{`"json:\"string\" # not" # comment`, `json:"string" # not`, true},
+		{`'a'b`, ``, false},
+		{`'a'# comment`, ``, false},
+		{`"a" b`, ``, false},
+		{`'a`, ``, false},
+		{`"a\"`, ``, false},
+		{`"\q"`, ``, false},
+	} {
+		value, err := yamlValue(test.input)
+		if (err == nil) != test.ok {
+			t.Errorf("%s: unexpected error: %v", test.input, err)
+		} else if value != test.expect {
+			t.Errorf("%s: expected %q; found %q", test.input, test.expect, value)
+		}
+	}
+}
+
+func TestFindConfig(t *testing.T) {
+	var dir = t.TempDir()
+
+	var mkdir = func(path string) string {
+		path = filepath.Join(dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(path, 0777); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	var touch = func(path string) string {
+		path = filepath.Join(dir, filepath.FromSlash(path))
+		if err := os.WriteFile(path, nil, 0666); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	mkdir("mod/pkg/sub")
+	mkdir("repo/.git")
+	mkdir("repo/pkg")
+	mkdir("none/pkg")
+	touch("golific.yaml") // Outside of every module and repository
+	touch("mod/go.mod")
+	touch("none/golific.json")
+
+	var inMod = touch("mod/pkg/golific.json")
+
+	for _, test := range []struct {
+		dir, expect string
+	}{
+		{"mod/pkg/sub", inMod},
+		{"mod/pkg", inMod},
+		{"mod", ""},
+		{"repo/pkg", ""},
+		{"none/pkg", ""},
+		{"none", filepath.Join(dir, "none", "golific.json")},
+	} {
+		if found := findConfig(filepath.Join(dir, test.dir)); found != test.expect {
+			t.Errorf("%s: expected %q; found %q", test.dir, test.expect, found)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const diffContext = 3 // Unchanged lines shown around each change
+
+type diffOp struct {
+	kind byte // ' ' for an unchanged line, '-' for removed and '+' for added
+	line string
+}
+
+/*
+Returns a unified diff that turns `old` into `new`, or an empty string if they
+are equal. Both sides are labeled with `name`.
+*/
+func unifiedDiff(name string, old, new []byte) string {
+	if bytes.Equal(old, new) {
+		return ""
+	}
+
+	var ops = groupChanges(diffLines(splitLines(old), splitLines(new)))
+
+	// The number of old and new lines that come before each op
+	var oldPos = make([]int, len(ops)+1)
+	var newPos = make([]int, len(ops)+1)
+
+	for i, op := range ops {
+		oldPos[i+1], newPos[i+1] = oldPos[i], newPos[i]
+		if op.kind != '+' {
+			oldPos[i+1]++
+		}
+		if op.kind != '-' {
+			newPos[i+1]++
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", name, name)
+
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		// Extend the hunk while the next change is close enough to share context
+		var start, end = max(0, i-diffContext), i
+		for j := i; j < len(ops) && j <= end+2*diffContext; j++ {
+			if ops[j].kind != ' ' {
+				end = j
+			}
+		}
+		end = min(len(ops), end+diffContext+1)
+
+		fmt.Fprintf(&buf, "@@ -%s +%s @@\n",
+			hunkRange(oldPos[start], oldPos[end]-oldPos[start]),
+			hunkRange(newPos[start], newPos[end]-newPos[start]))
+
+		for _, op := range ops[start:end] {
+			buf.WriteByte(op.kind)
+			buf.WriteString(op.line)
+			buf.WriteByte('\n')
+		}
+
+		i = end
+	}
+
+	return buf.String()
+}
+
+// Moves the removed lines of each run of changes before the added lines, as
+// diff tools print them, without changing the order of either.
+func groupChanges(ops []diffOp) []diffOp {
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		var j = i
+		for j < len(ops) && ops[j].kind != ' ' {
+			j++
+		}
+
+		sort.SliceStable(ops[i:j], func(x, y int) bool {
+			return ops[i+x].kind == '-' && ops[i+y].kind == '+'
+		})
+		i = j
+	}
+	return ops
+}
+
+func hunkRange(before, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
+
+func splitLines(b []byte) []string {
+	if len(b) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+}
+
+/*
+Finds the shortest edit script between the lines using Myers' O(ND) algorithm.
+Common leading and trailing lines are set aside first, and the rest is split
+at the middle of the script until one side is empty, which keeps the memory
+linear in the number of lines however much they differ.
+*/
+func diffLines(a, b []string) (ops []diffOp) {
+	var pre, suf = 0, 0
+
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	for suf < len(a)-pre && suf < len(b)-pre &&
+		a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+
+	for _, line := range a[:pre] {
+		ops = append(ops, diffOp{' ', line})
+	}
+
+	var ma, mb = a[pre : len(a)-suf], b[pre : len(b)-suf]
+
+	switch {
+	case len(ma) == 0:
+		for _, line := range mb {
+			ops = append(ops, diffOp{'+', line})
+		}
+	case len(mb) == 0:
+		for _, line := range ma {
+			ops = append(ops, diffOp{'-', line})
+		}
+	default:
+		if x, y, ok := middleSnake(ma, mb); ok {
+			ops = append(ops, diffLines(ma[:x], mb[:y])...)
+			ops = append(ops, diffLines(ma[x:], mb[y:])...)
+			break
+		}
+		for _, line := range ma {
+			ops = append(ops, diffOp{'-', line})
+		}
+		for _, line := range mb {
+			ops = append(ops, diffOp{'+', line})
+		}
+	}
+
+	for _, line := range a[len(a)-suf:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+
+	return ops
+}
+
+/*
+Returns a point in the middle of a shortest edit script between `a` and `b`,
+found by searching from both ends until the paths meet. Only the furthest
+reaching path of each diagonal is kept. The lines must differ at both ends.
+Returns 'false' if they have no line in common.
+*/
+func middleSnake(a, b []string) (int, int, bool) {
+	var n, m = len(a), len(b)
+	var maxD = (n + m + 1) / 2
+	var offset = maxD + 1
+
+	// The furthest x reached on each diagonal k, from the start and from the end
+	var fwd, bwd = make([]int, 2*offset+1), make([]int, 2*offset+1)
+
+	for i := range fwd {
+		fwd[i], bwd[i] = -1, -1
+	}
+	fwd[offset+1], bwd[offset+1] = 0, 0
+
+	var delta = n - m
+	var odd = delta%2 != 0
+
+	// Diagonals that went past the edges are no longer searched
+	var fStart, fEnd, bStart, bEnd int
+
+	for d := 0; d < maxD; d++ {
+		for k := -d + fStart; k <= d-fEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && fwd[offset+k-1] < fwd[offset+k+1]) {
+				x = fwd[offset+k+1]
+			} else {
+				x = fwd[offset+k-1] + 1
+			}
+
+			var y = x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			fwd[offset+k] = x
+
+			if x > n {
+				fEnd += 2
+			} else if y > m {
+				fStart += 2
+			} else if odd {
+				if bk := offset + delta - k; bk >= 0 && bk < len(bwd) && bwd[bk] != -1 {
+					if x >= n-bwd[bk] {
+						return x, y, true
+					}
+				}
+			}
+		}
+
+		for k := -d + bStart; k <= d-bEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && bwd[offset+k-1] < bwd[offset+k+1]) {
+				x = bwd[offset+k+1]
+			} else {
+				x = bwd[offset+k-1] + 1
+			}
+
+			var y = x - k
+			for x < n && y < m && a[n-x-1] == b[m-y-1] {
+				x, y = x+1, y+1
+			}
+			bwd[offset+k] = x
+
+			if x > n {
+				bEnd += 2
+			} else if y > m {
+				bStart += 2
+			} else if !odd {
+				if fk := offset + delta - k; fk >= 0 && fk < len(fwd) && fwd[fk] != -1 {
+					var fx = fwd[fk]
+					if fx >= n-x {
+						return fx, fx - (fk - offset), true
+					}
+				}
+			}
+		}
+	}
+
+	return 0, 0, false
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// Joins the lines, each followed by a newline.
+func lines(l ...string) string {
+	if len(l) == 0 {
+		return ""
+	}
+	return strings.Join(l, "\n") + "\n"
+}
+
+// Returns the lines of `l`, with those at the indexes of `set` replaced, and
+// those whose replacement is empty removed.
+func edit(l []string, set map[int]string) []string {
+	var res []string
+	for i, line := range l {
+		if s, ok := set[i]; !ok {
+			res = append(res, line)
+		} else if s != "" {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+func repeat(line string, n int) []string {
+	var res = make([]string, n)
+	for i := range res {
+		res[i] = line
+	}
+	return res
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	var xs = repeat("x", 30)
+	var letters = strings.Split("abcdefghij", "")
+
+	for _, test := range []struct {
+		name, old, new, expect string
+	}{
+		{"identical", lines("a", "b"), lines("a", "b"), ""},
+		{"both empty", "", "", ""},
+
+		{"from empty", "", lines("a", "b"), lines(
+			"@@ -0,0 +1,2 @@", "+a", "+b",
+		)},
+
+		{"to empty", lines("a", "b"), "", lines(
+			"@@ -1,2 +0,0 @@", "-a", "-b",
+		)},
+
+		{"single line", lines("a", "b", "c"), lines("a", "x", "c"), lines(
+			"@@ -1,3 +1,3 @@", " a", "-b", "+x", " c",
+		)},
+
+		{"removed before added", lines("a", "c", "a"), lines("c", "b"), lines(
+			"@@ -1,3 +1,2 @@", "-a", " c", "-a", "+b",
+		)},
+
+		{"repeated lines",
+			lines(append(append(repeat("x", 10), "a"), repeat("x", 10)...)...),
+			lines(append(append(repeat("x", 10), "b"), repeat("x", 10)...)...),
+			lines("@@ -8,7 +8,7 @@", " x", " x", " x", "-a", "+b", " x", " x", " x"),
+		},
+
+		{"separate hunks",
+			lines(edit(xs, map[int]string{2: "a", 26: "b"})...),
+			lines(edit(xs, map[int]string{2: "", 26: "c"})...),
+			lines(
+				"@@ -1,6 +1,5 @@", " x", " x", "-a", " x", " x", " x",
+				"@@ -24,7 +23,7 @@", " x", " x", " x", "-b", "+c", " x", " x", " x",
+			),
+		},
+
+		{"merged hunk",
+			lines(letters...),
+			lines(edit(letters, map[int]string{1: "B", 7: "H"})...),
+			lines(
+				"@@ -1,10 +1,10 @@", " a", "-b", "+B", " c", " d", " e", " f", " g",
+				"-h", "+H", " i", " j",
+			),
+		},
+	} {
+		var expect = test.expect
+		if expect != "" {
+			expect = "--- f.go\n+++ f.go\n" + expect
+		}
+
+		if found := unifiedDiff("f.go", []byte(test.old), []byte(test.new)); found != expect {
+			t.Errorf("%s: expected:\n%s\nfound:\n%s", test.name, expect, found)
+		}
+	}
+}
+
+// Checks that the edit script of long inputs made of few distinct lines turns
+// one into the other, and is as short as the longest common subsequence allows.
+func TestDiffLinesMinimal(t *testing.T) {
+	var r = rand.New(rand.NewSource(1))
+
+	var random = func() []string {
+		var l = make([]string, r.Intn(300))
+		for i := range l {
+			l[i] = string(rune('a' + r.Intn(4)))
+		}
+		return l
+	}
+
+	for i := 0; i < 50; i++ {
+		var a, b = random(), random()
+		var ops = diffLines(a, b)
+		var oldLines, newLines []string
+		var changes int
+
+		for _, op := range ops {
+			if op.kind != '+' {
+				oldLines = append(oldLines, op.line)
+			}
+			if op.kind != '-' {
+				newLines = append(newLines, op.line)
+			}
+			if op.kind != ' ' {
+				changes++
+			}
+		}
+
+		if strings.Join(oldLines, "\n") != strings.Join(a, "\n") ||
+			strings.Join(newLines, "\n") != strings.Join(b, "\n") {
+			t.Fatalf("the edit script doesn't turn %q into %q", a, b)
+		}
+
+		if expect := len(a) + len(b) - 2*lcsLength(a, b); changes != expect {
+			t.Errorf("expected %d changed lines; found %d", expect, changes)
+		}
+	}
+}
+
+func lcsLength(a, b []string) int {
+	var prev, cur = make([]int, len(b)+1), make([]int, len(b)+1)
+
+	for i := range a {
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(cur[j], prev[j+1])
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
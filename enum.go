@@ -1,17 +1,23 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"go/ast"
 	"go/token"
+	"math"
+	"sort"
 	"strconv"
 	"strings"
 )
 
 type EnumDefaults struct {
 	BaseRepr
-	FlagSep  string // ""
-	iterName string // "Values"
+	FlagSep   string // ""
+	iterName  string // "Values"
+	valueName string // "Value"
+	intType   string // "" (sized automatically)
+	Meta      []*EnumMetaColumn
 }
 
 type EnumRepr struct {
@@ -22,27 +28,94 @@ type EnumRepr struct {
 type EnumFieldRepr struct {
 	BaseFieldRepr
 	String      string
+	Aliases     []string // Other strings accepted when unmarshaling
 	Description string
-	Value       int64
+	Value       int64             // Holds the bits of a uint64 if the bigValue flag is set
+	meta        map[string]string // Go literals of metadata, by column name
 }
 
+// EnumMetaColumn is a column of metadata declared using the 'meta' option, for
+// which each variant may set a value using the 'gMeta' field tag.
+type EnumMetaColumn struct {
+	Name string
+	Type string
+}
+
+// EnumPrefixCase is a lowercase string or alias of a variant, as a quoted Go
+// literal, along with the variant's value.
+type EnumPrefixCase struct {
+	Lit   string
+	Value string
+}
+
+// The built-in defaults, before any config file or @enum-defaults is applied
 var enumDefaults EnumDefaults
 
 func init() {
 	enumDefaults.FlagSep = ""
 	enumDefaults.iterName = "Values"
-	enumDefaults.flags = 0
+	enumDefaults.valueName = "Value"
+	enumDefaults.flags = textMarshalIsString | textUnmarshalIsString
 }
 
 func (self *EnumRepr) GetUniqueName() string {
 	return "value_" + self.getUniqueId()
 }
+func (self *EnumRepr) GetRawName() string {
+	return "raw_" + self.getUniqueId()
+}
 
 func (efr *EnumFieldRepr) LowerString() string { return strings.ToLower(efr.String) }
 
+// ValueLit returns the value of the variant as a Go literal.
+func (efr *EnumFieldRepr) ValueLit() string {
+	if efr.isBig() {
+		return strconv.FormatUint(uint64(efr.Value), 10)
+	}
+	return strconv.FormatInt(efr.Value, 10)
+}
+
+func (efr *EnumFieldRepr) isBig() bool { return efr.flags&bigValue == bigValue }
+
+// CaseStrings returns the lowercase string and aliases of the variant as a
+// list of quoted strings, for use in a 'case' clause.
+func (efr *EnumFieldRepr) CaseStrings() string {
+	var quoted = []string{strconv.Quote(efr.LowerString())}
+
+	for _, alias := range efr.Aliases {
+		quoted = append(quoted, strconv.Quote(strings.ToLower(alias)))
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// PrefixCases returns the strings and aliases of all the variants, longest
+// first, for splitting bitflags that were joined without a separator.
+func (self *EnumRepr) PrefixCases() []EnumPrefixCase {
+	var cases []EnumPrefixCase
+
+	for _, f := range self.Fields {
+		for _, str := range append([]string{f.String}, f.Aliases...) {
+			if str = strings.ToLower(str); len(str) != 0 {
+				cases = append(cases, EnumPrefixCase{str, f.ValueLit()})
+			}
+		}
+	}
+
+	sort.SliceStable(cases, func(i, j int) bool {
+		return len(cases[i].Lit) > len(cases[j].Lit)
+	})
+
+	for i := range cases {
+		cases[i].Lit = strconv.Quote(cases[i].Lit)
+	}
+	return cases
+}
+
 func (self *EnumRepr) DoJson() bool    { return self.flags&dropJson == 0 }
 func (self *EnumRepr) DoXml() bool     { return self.flags&dropXml == 0 }
 func (self *EnumRepr) IsBitflag() bool { return self.flags&bitflags == bitflags }
+func (self *EnumRepr) IsStrict() bool  { return self.flags&strict == strict }
+func (self *EnumRepr) IsOpen() bool    { return self.flags&open == open }
 func (self *EnumRepr) HasDefault() bool {
 	return self.flags&hasDefault == hasDefault
 }
@@ -54,6 +127,71 @@ func (self *EnumRepr) JsonUnmarshalIsString() bool {
 	return self.flags&jsonUnmarshalIsString == jsonUnmarshalIsString
 }
 
+func (self *EnumRepr) DoText() bool { return self.flags&dropText == 0 }
+
+func (self *EnumRepr) TextMarshalIsString() bool {
+	return self.flags&textMarshalIsString == textMarshalIsString
+}
+func (self *EnumRepr) TextUnmarshalIsString() bool {
+	return self.flags&textUnmarshalIsString == textUnmarshalIsString
+}
+
+func (self *EnumRepr) XmlMarshalIsString() bool {
+	return self.flags&xmlMarshalIsString == xmlMarshalIsString
+}
+func (self *EnumRepr) XmlUnmarshalIsString() bool {
+	return self.flags&xmlUnmarshalIsString == xmlUnmarshalIsString
+}
+
+func (self *EnumRepr) DoSql() bool { return self.flags&doSql == doSql }
+func (self *EnumRepr) SqlIsString() bool {
+	return self.flags&sqlIsString == sqlIsString
+}
+
+// UnmarshalsString returns true if any of the unmarshalers of the enum expects
+// the string value of the variants.
+func (self *EnumRepr) UnmarshalsString() bool {
+	return self.flags&(dropJson|jsonUnmarshalIsString) == jsonUnmarshalIsString ||
+		self.flags&(dropText|textUnmarshalIsString) == textUnmarshalIsString ||
+		self.flags&(dropXml|xmlUnmarshalIsString) == xmlUnmarshalIsString ||
+		self.flags&(doSql|sqlIsString) == doSql|sqlIsString ||
+		self.flags&(dropJson|open) == open // JSON strings are accepted in either mode
+}
+
+// UnmarshalsNumber returns true if any of the unmarshalers of the enum expects
+// the numeric value of the variants.
+func (self *EnumRepr) UnmarshalsNumber() bool {
+	return self.flags&(dropJson|jsonUnmarshalIsString) == 0 ||
+		self.flags&(dropText|textUnmarshalIsString) == 0 ||
+		self.flags&(dropXml|xmlUnmarshalIsString) == 0 ||
+		self.flags&doSql == doSql || // Scan accepts an int64 in either mode
+		self.flags&(dropJson|open) == open // JSON numbers are accepted in either mode
+}
+
+func (self *EnumRepr) GetValueName() string {
+	if len(self.valueName) == 0 {
+		return "Value"
+	}
+	return self.valueName
+}
+
+// MetaValue returns the Go literal of the variant's value for the given column
+// of metadata, or an empty string if no value was set.
+func (efr *EnumFieldRepr) MetaValue(column string) string {
+	return efr.meta[column]
+}
+
+// Zero returns the Go literal of the zero value of the column's type.
+func (col *EnumMetaColumn) Zero() string {
+	switch col.Type {
+	case "bool":
+		return "false"
+	case "string":
+		return `""`
+	}
+	return "0"
+}
+
 func (self *EnumRepr) GetIterName() string {
 	if len(self.iterName) == 0 {
 		return "Values"
@@ -62,19 +200,138 @@ func (self *EnumRepr) GetIterName() string {
 }
 
 func (repr *EnumRepr) GetIntType() string {
-	var bf = repr.flags&bitflags == bitflags
-	var ln = int64(len(repr.Fields))
+	if len(repr.intType) > 0 {
+		return repr.intType
+	}
 
-	switch {
-	case (bf && ln <= 8) || ln < 256:
-		return "uint8"
-	case (bf && ln <= 16) || ln < 65536:
-		return "uint16"
-	case (bf && ln <= 32) || ln < 4294967296:
-		return "uint32"
+	var min, max int64
+	var big bool
+
+	for _, f := range repr.Fields {
+		if f.isBig() {
+			big = true
+			continue
+		}
+		if f.Value < min {
+			min = f.Value
+		}
+		if f.Value > max {
+			max = f.Value
+		}
+	}
+
+	if big {
+		return "uint64"
+	}
+
+	// The smallest type that holds every value
+	for _, bits := range [...]uint{8, 16, 32} {
+		if min < 0 {
+			if -1<<(bits-1) <= min && max < 1<<(bits-1) {
+				return "int" + strconv.Itoa(int(bits))
+			}
+		} else if max < 1<<bits {
+			return "uint" + strconv.Itoa(int(bits))
+		}
+	}
+
+	if min < 0 {
+		return "int64"
 	}
 	return "uint64"
 }
+func (repr *EnumRepr) GetIntBits() int {
+	return intBits(repr.GetIntType())
+}
+
+func (repr *EnumRepr) IsSigned() bool {
+	return !strings.HasPrefix(repr.GetIntType(), "uint")
+}
+
+// GetParseFunc returns the name of the strconv function that parses a number
+// of the enum's type.
+func (repr *EnumRepr) GetParseFunc() string {
+	if repr.IsSigned() {
+		return "ParseInt"
+	}
+	return "ParseUint"
+}
+
+// GetFormatValue returns the code that formats the value of the receiver of a
+// method as a string.
+func (repr *EnumRepr) GetFormatValue() string {
+	if repr.IsSigned() {
+		return "strconv.FormatInt(int64(self." + repr.GetUniqueName() + "), 10)"
+	}
+	return "strconv.FormatUint(uint64(self." + repr.GetUniqueName() + "), 10)"
+}
+
+// Returns an error if the value of the variant does not fit in the enum's type.
+func (repr *EnumRepr) checkFits(f *EnumFieldRepr) error {
+	var typ = repr.GetIntType()
+	var bits = uint(intBits(typ))
+	var fits bool
+
+	switch {
+	case f.isBig():
+		fits = typ == "uint64"
+	case repr.IsSigned():
+		fits = bits == 64 || (-1<<(bits-1) <= f.Value && f.Value < 1<<(bits-1))
+	default:
+		fits = f.Value >= 0 && (bits == 64 || f.Value < 1<<bits)
+	}
+
+	if !fits {
+		return f.errorf("The value %s of %q does not fit in the type %s",
+			f.ValueLit(), f.Name, typ)
+	}
+	return nil
+}
+
+// Reports every variant whose value or string is the same as that of a previous
+// variant, or whose value does not fit in the enum's type. Strings are compared
+// ignoring case, as they are when unmarshaling.
+func (repr *EnumRepr) validate() error {
+	var errs []error
+	var values = make(map[int64]*EnumFieldRepr, len(repr.Fields))
+	var strs = make(map[string]*EnumFieldRepr, len(repr.Fields))
+
+	if repr.IsBitflag() && len(repr.Fields) > 63 {
+		errs = append(errs, repr.errorf("bitflags may have at most 63 variants"))
+	}
+
+	for _, f := range repr.Fields {
+		if other, ok := values[f.Value]; ok {
+			var how = "assigned"
+			if f.flags&(hasCustomValue|hasDefault) == 0 {
+				how = "automatically assigned"
+			}
+
+			errs = append(errs, f.errorf("The %s value %s of %q is already used by %q",
+				how, f.ValueLit(), f.Name, other.Name))
+		} else {
+			values[f.Value] = f
+		}
+
+		if other, ok := strs[f.LowerString()]; ok {
+			errs = append(errs, f.errorf("The string %q of %q is already used by %q, "+
+				"ignoring case", f.String, f.Name, other.Name))
+		} else {
+			strs[f.LowerString()] = f
+		}
+
+		if err := repr.checkFits(f); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := repr.checkAliases(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
 func (self *EnumRepr) GetDefaultValue() int64 {
 	for _, f := range self.Fields {
 		if f.flags&hasDefault == hasDefault {
@@ -85,7 +342,11 @@ func (self *EnumRepr) GetDefaultValue() int64 {
 }
 
 func (self *FileData) doEnumDefaults(tagText string) error {
-	return enumDefaults.gatherFlags(tagText)
+	if tagText, ok := cutReset(tagText); ok {
+		self.enumDefaults = self.config.enumDefaults
+		return self.enumDefaults.gatherFlags(tagText)
+	}
+	return self.enumDefaults.gatherFlags(tagText)
 }
 
 func (ed *EnumDefaults) gatherFlags(tagText string) error {
@@ -102,6 +363,41 @@ func (ed *EnumDefaults) gatherFlags(tagText string) error {
 		case "iterator_name": // Custom Name for Array of values
 			ed.iterName = flag.Value
 
+		case "value_method": // Custom Name for the method returning the number
+			if ed.valueName, err = flag.getNonEmpty(); err != nil {
+				return err
+			}
+			if !token.IsIdentifier(ed.valueName) {
+				return fmt.Errorf("%q is not a valid method name", ed.valueName)
+			}
+
+		case "type": // The type of the enum's underlying values
+			if ed.intType, err = flag.getNonEmpty(); err != nil {
+				return err
+			}
+			switch ed.intType {
+			case "int8", "int16", "int32", "int64",
+				"uint8", "uint16", "uint32", "uint64":
+			default:
+				return fmt.Errorf("Unexpected value %q for %q; a sized integer type "+
+					"is expected", flag.Value, flag.Name)
+			}
+
+		case "meta": // Declare columns of metadata, like "Status int, Retry bool"
+			if _, err = flag.getNonEmpty(); err != nil {
+				return err
+			}
+			if ed.Meta, err = parseMetaColumns(flag.Value); err != nil {
+				return err
+			}
+
+		case "sql": // Set type of SQL Scanner and Valuer
+			ed.flags |= doSql
+			return ed.setMarshal(flag, sqlIsString)
+
+		case "drop_sql": // Do not generate SQL methods
+			ed.flags &^= doSql
+
 		case "json": // Set type of JSON marshaler and unmarshaler
 			return ed.setMarshal(flag, jsonMarshalIsString|jsonUnmarshalIsString)
 
@@ -114,6 +410,39 @@ func (ed *EnumDefaults) gatherFlags(tagText string) error {
 		case "drop_json": // Do not generate JSON marshaling methods
 			return ed.doBooleanFlag(flag, dropJson)
 
+		case "random_id": // Name the private field with a new random id every run
+			return ed.doBooleanFlag(flag, randomId)
+
+		case "strict": // Unmarshalers return errors for unknown values
+			return ed.doBooleanFlag(flag, strict)
+
+		case "open": // Unmarshalers keep unknown values
+			return ed.doBooleanFlag(flag, open)
+
+		case "xml": // Set type of XML marshaler and unmarshaler
+			return ed.setMarshal(flag, xmlMarshalIsString|xmlUnmarshalIsString)
+
+		case "xml_marshal": // Set type of XML marshaler
+			return ed.setMarshal(flag, xmlMarshalIsString)
+
+		case "xml_unmarshal": // Set type of XML unmarshaler
+			return ed.setMarshal(flag, xmlUnmarshalIsString)
+
+		case "drop_xml": // Do not generate XML marshaling methods
+			return ed.doBooleanFlag(flag, dropXml)
+
+		case "text": // Set type of text marshaler and unmarshaler
+			return ed.setMarshal(flag, textMarshalIsString|textUnmarshalIsString)
+
+		case "text_marshal": // Set type of text marshaler
+			return ed.setMarshal(flag, textMarshalIsString)
+
+		case "text_unmarshal": // Set type of text unmarshaler
+			return ed.setMarshal(flag, textUnmarshalIsString)
+
+		case "drop_text": // Do not generate text marshaling methods
+			return ed.doBooleanFlag(flag, dropText)
+
 		default:
 			return UnknownFlag
 		}
@@ -127,7 +456,7 @@ func (self *FileData) newEnum(fset *token.FileSet, tagText string,
 	var err error
 
 	enum := EnumRepr{
-		EnumDefaults: enumDefaults, // copy of current defaults
+		EnumDefaults: self.enumDefaults, // copy of current defaults
 	}
 	enum.fset = fset
 
@@ -136,27 +465,44 @@ func (self *FileData) newEnum(fset *token.FileSet, tagText string,
 	}
 
 	if err = enum.gatherFlags(tagText); err != nil {
-		return err
+		return enum.positioned(err)
+	}
+
+	enum.unique = self.uniqueId(&enum.Base, enum.Name)
+
+	if enum.IsStrict() && enum.IsOpen() {
+		return enum.errorf("The 'strict' and 'open' options can not be combined")
+	}
+
+	for _, col := range enum.Meta {
+		if enum.isMethodName(col.Name) {
+			return enum.errorf("The metadata column %q conflicts with a method of "+
+				"the enum", col.Name)
+		}
+	}
+
+	if enum.DoSql() && enum.GetValueName() == "Value" {
+		return enum.errorf("The 'sql' option needs the Value method for " +
+			"driver.Valuer. Use `value_method:\"SomeOtherIdent\"` to rename " +
+			"the method returning the number.")
 	}
 
 	if err = enum.doFields(strct.Fields); err != nil {
 		return err
 	}
 
-	self.Enums = append(self.Enums, &enum)
-
-	var def string
+	if err = enum.validate(); err != nil {
+		return err
+	}
 
 	for _, f := range enum.Fields {
-		if enum.flags&hasDefault == hasDefault { // Only one --default variant allowed
-			if len(def) > 0 {
-				return fmt.Errorf("--default was previously defined on %q", def)
-			}
-
+		if f.flags&hasDefault == hasDefault {
 			enum.flags |= hasDefault // Needed for `IsDefault()` method.
-			def = f.Name
 		}
 	}
+
+	self.Enums = append(self.Enums, &enum)
+
 	return nil
 }
 
@@ -166,21 +512,25 @@ func (self *EnumRepr) doFields(fields *ast.FieldList) (err error) {
 		f.fset = self.fset
 
 		if err = f.gatherCodeCommentsAndName(field, false); err != nil {
-			return err
+			return f.positioned(err)
 		}
 
 		if f.Name == self.iterName {
-			return fmt.Errorf("The variant named %q conflicts with the iterator. Use "+
+			return f.errorf("The variant named %q conflicts with the iterator. Use "+
 				"`--iterator_name=SomeOtherIdent` to resolve the conflict.", f.Name)
 		}
 
 		// Flags come from the struct field tag
 		if err = f.gatherFlags(getFlags(field.Tag)); err != nil {
-			return err
+			return f.positioned(err)
+		}
+
+		if err = f.checkMeta(self.Meta); err != nil {
+			return f.positioned(err)
 		}
 
 		if self.flags&bitflags == bitflags && f.Value != 0 {
-			return fmt.Errorf("bitflags may not have a custom --value")
+			return f.errorf("bitflags may not have a custom --value")
 		}
 
 		// Set values if no string or description value is given
@@ -197,7 +547,6 @@ func (self *EnumRepr) doFields(fields *ast.FieldList) (err error) {
 			if self.flags&bitflags == bitflags {
 				f.Value = 1 << uint(len(self.Fields))
 			} else {
-				// TODO: Make sure there are no Custom number conflicts
 				f.Value = int64(len(self.Fields) + 1)
 			}
 		}
@@ -206,7 +555,42 @@ func (self *EnumRepr) doFields(fields *ast.FieldList) (err error) {
 	}
 
 	if len(self.Fields) == 0 {
-		return fmt.Errorf("Enums must have at least one variant defined")
+		return self.errorf("Enums must have at least one variant defined")
+	}
+
+	return nil
+}
+
+// Makes sure that no alias matches the string or an alias of another variant,
+// ignoring case, since either would be ambiguous when unmarshaling.
+func (self *EnumRepr) checkAliases() error {
+	var seen = make(map[string]*EnumFieldRepr, len(self.Fields))
+
+	for _, f := range self.Fields {
+		if _, ok := seen[f.LowerString()]; !ok {
+			seen[f.LowerString()] = f
+		}
+	}
+
+	for _, f := range self.Fields {
+		var aliases = f.Aliases[:0]
+
+		for _, alias := range f.Aliases {
+			var lower = strings.ToLower(alias)
+
+			if other, ok := seen[lower]; !ok {
+				seen[lower] = f
+			} else if other != f {
+				return f.errorf("The alias %q of %q collides with the string or an "+
+					"alias of %q", alias, f.Name, other.Name)
+			} else {
+				continue // Same as its own string or alias, so it's dropped.
+			}
+
+			aliases = append(aliases, alias)
+		}
+
+		f.Aliases = aliases
 	}
 
 	return nil
@@ -236,27 +620,44 @@ func (self *EnumFieldRepr) gatherFlags(tagText string) error {
 				return err
 			}
 
+		case "gAliases": // Other strings accepted when unmarshaling the field
+			if self.Aliases, err = getAliases(flag); err != nil {
+				return err
+			}
+
+		case "gMeta": // Metadata values, like "Status=404,Retry=true"
+			if _, err = flag.getNonEmpty(); err != nil {
+				return err
+			}
+			if self.meta, err = parseMetaValues(flag.Value); err != nil {
+				return err
+			}
+
 		case "gValue": // Custom value for the field
 			if _, err = flag.getWithColon(); err != nil {
 				return err
 			}
 
-			if n, err := strconv.ParseUint(flag.Value, 10, 32); err != nil {
-				return fmt.Errorf("%q is not a valid uint", flag.Value)
+			if n, err := strconv.ParseInt(flag.Value, 10, 64); err == nil {
+				self.Value = n
 
-			} else {
-				self.Value = int64(n)
+			} else if u, err := strconv.ParseUint(flag.Value, 10, 64); err == nil {
+				self.Value = int64(u)
+				self.flags |= bigValue
 
-				if self.flags&hasDefault == hasDefault {
-					return fmt.Errorf(errCustomDefault)
-				}
+			} else {
+				return fmt.Errorf("%q is not a valid 64-bit integer", flag.Value)
+			}
 
-				if self.Value == 0 {
-					return fmt.Errorf("The 0 value is reserved for the gDefault flag.")
-				}
+			if self.flags&hasDefault == hasDefault {
+				return fmt.Errorf(errCustomDefault)
+			}
 
-				self.flags |= hasCustomValue
+			if self.Value == 0 {
+				return fmt.Errorf("The 0 value is reserved for the gDefault flag.")
 			}
+
+			self.flags |= hasCustomValue
 		default:
 			return UnknownFlag
 		}
@@ -264,6 +665,177 @@ func (self *EnumFieldRepr) gatherFlags(tagText string) error {
 	})
 }
 
+// Parses the declaration of metadata columns given to the 'meta' option, which
+// is a comma separated list of names, each followed by a type.
+func parseMetaColumns(decl string) (columns []*EnumMetaColumn, err error) {
+	var seen = make(map[string]bool)
+
+	for _, part := range strings.Split(decl, ",") {
+		var fields = strings.Fields(part)
+
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("Expected a name and type for metadata; found %q",
+				strings.TrimSpace(part))
+		}
+
+		var col = EnumMetaColumn{Name: fields[0], Type: fields[1]}
+
+		if !token.IsIdentifier(col.Name) || !isExportedIdent(col.Name) {
+			return nil, fmt.Errorf("%q is not a valid exported method name", col.Name)
+		}
+		if seen[col.Name] {
+			return nil, fmt.Errorf("The metadata column %q is declared twice", col.Name)
+		}
+		seen[col.Name] = true
+
+		if _, err = col.literal(col.Zero()); err != nil {
+			return nil, fmt.Errorf("Unsupported type %q for metadata column %q",
+				col.Type, col.Name)
+		}
+
+		columns = append(columns, &col)
+	}
+	return columns, nil
+}
+
+// Parses the metadata values of a 'gMeta' tag, which is a comma separated list
+// of column=value pairs. Values holding commas may be put in single quotes.
+// The values are validated later by `checkMeta`.
+func parseMetaValues(text string) (map[string]string, error) {
+	var values = make(map[string]string)
+
+	for text = strings.TrimSpace(text); len(text) > 0; {
+		var idx = strings.IndexByte(text, '=')
+		if idx == -1 {
+			return nil, fmt.Errorf("Expected '=' after metadata column in %q", text)
+		}
+
+		var name, value = strings.TrimSpace(text[:idx]), ""
+		text = strings.TrimSpace(text[idx+1:])
+
+		if strings.HasPrefix(text, "'") {
+			if idx = strings.IndexByte(text[1:], '\''); idx == -1 {
+				return nil, fmt.Errorf("Expected closing quote for metadata %q", name)
+			}
+			value, text = text[1:idx+1], strings.TrimSpace(text[idx+2:])
+
+		} else if idx = strings.IndexByte(text, ','); idx == -1 {
+			value, text = strings.TrimSpace(text), ""
+
+		} else {
+			value, text = strings.TrimSpace(text[:idx]), text[idx:]
+		}
+
+		if len(text) > 0 {
+			if text[0] != ',' {
+				return nil, fmt.Errorf("Expected ',' after metadata %q", name)
+			}
+			text = strings.TrimSpace(text[1:])
+		}
+
+		if _, ok := values[name]; ok {
+			return nil, fmt.Errorf("The metadata %q is set twice", name)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// Makes sure every metadata value of the variant belongs to a declared column,
+// and replaces the values with Go literals of the column's type.
+func (self *EnumFieldRepr) checkMeta(columns []*EnumMetaColumn) error {
+	for name, value := range self.meta {
+		var col *EnumMetaColumn
+
+		for _, c := range columns {
+			if c.Name == name {
+				col = c
+				break
+			}
+		}
+
+		if col == nil {
+			return fmt.Errorf("The metadata %q of %q is not declared with the "+
+				"'meta' option", name, self.Name)
+		}
+
+		lit, err := col.literal(value)
+		if err != nil {
+			return fmt.Errorf("Invalid metadata %q of %q: %s", name, self.Name, err)
+		}
+		self.meta[name] = lit
+	}
+	return nil
+}
+
+// Returns the Go literal for the given value of the column's type.
+func (col *EnumMetaColumn) literal(value string) (string, error) {
+	switch col.Type {
+	case "string":
+		return strconv.Quote(value), nil
+
+	case "bool":
+		b, err := strconv.ParseBool(value)
+		return strconv.FormatBool(b), err
+
+	case "int", "int8", "int16", "int32", "int64":
+		n, err := strconv.ParseInt(value, 0, intBits(col.Type))
+		return strconv.FormatInt(n, 10), err
+
+	case "uint", "uint8", "uint16", "uint32", "uint64":
+		n, err := strconv.ParseUint(value, 0, intBits(col.Type))
+		return strconv.FormatUint(n, 10), err
+
+	case "float32", "float64":
+		f, err := strconv.ParseFloat(value, intBits(col.Type))
+		if err == nil && (math.IsInf(f, 0) || math.IsNaN(f)) {
+			err = fmt.Errorf("%q has no Go literal", value)
+		}
+		return strconv.FormatFloat(f, 'g', -1, intBits(col.Type)), err
+	}
+	return "", fmt.Errorf("Unsupported type %q", col.Type)
+}
+
+// Returns the size in bits of a sized numeric type name, or 64 if unsized.
+func intBits(typ string) int {
+	if bits, err := strconv.Atoi(strings.TrimLeft(typ, "uintfloa")); err == nil {
+		return bits
+	}
+	return 64
+}
+
+// Reports whether the name is already used by a method generated for the enum.
+func (self *EnumRepr) isMethodName(name string) bool {
+	switch name {
+	case self.GetValueName(), "IntValue", "Name", "Type", "Namespace",
+		"IsDefault", "IsZero", "String", "Description", "Match", "JSONEncode",
+		"JSONDecode", "MarshalJSON", "UnmarshalJSON", "MarshalText",
+		"UnmarshalText", "MarshalXML", "UnmarshalXML", "MarshalXMLAttr", "UnmarshalXMLAttr",
+		"Scan", "Value", "IsUnknown", "Unknown",
+		"Add", "AddAll", "Remove", "RemoveAll", "Has", "HasAny", "HasAll":
+		return true
+	}
+	return false
+}
+
+// Gets the comma separated list of aliases from the value of the flag.
+func getAliases(flag Flag) (aliases []string, err error) {
+	if _, err = flag.getNonEmpty(); err != nil {
+		return nil, err
+	}
+
+	for _, alias := range strings.Split(flag.Value, ",") {
+		if alias = strings.TrimSpace(alias); len(alias) > 0 {
+			aliases = append(aliases, alias)
+		}
+	}
+
+	if len(aliases) == 0 {
+		return nil, fmt.Errorf("%q requires at least one alias", flag.Name)
+	}
+	return aliases, nil
+}
+
 func (self *EnumDefaults) setMarshal(flag Flag, flags uint) error {
 	if _, err := flag.getNonEmpty(); err != nil {
 		return err
@@ -279,27 +851,56 @@ func (self *EnumDefaults) setMarshal(flag Flag, flags uint) error {
 	return nil
 }
 
+// Returns true if the code generated for the enum formats or parses numbers or
+// quoted strings, which only the marshalers, unmarshalers and the methods that
+// handle unknown values do.
+func (self *EnumRepr) usesStrconv() bool {
+	return self.DoJson() || self.DoText() || self.DoXml() ||
+		self.UnmarshalsNumber() || self.IsOpen()
+}
+
 func (self *FileData) GatherEnumImports() {
 	if len(self.Enums) == 0 {
 		return
 	}
-	self.Imports["strconv"] = true
+	self.Imports["strings"] = true
 	self.Imports["Golific/gJson"] = true
+	self.Imports["Golific/gEnum"] = true
+
+	for _, repr := range self.Enums {
+		if repr.usesStrconv() {
+			self.Imports["strconv"] = true
+			break
+		}
+	}
+
+	for _, repr := range self.Enums {
+		if repr.DoSql() {
+			self.Imports["database/sql/driver"] = true
+			self.Imports["fmt"] = true
+			break
+		}
+	}
 
-	// If any EnumRepr is includes a JSON unmarshaler, "log" is needed
 	for _, repr := range self.Enums {
-		// If we don't dropJson and we are unmarshaling as a string, we need "log"
-		if repr.flags&(dropJson|jsonUnmarshalIsString) == jsonUnmarshalIsString {
+		if repr.DoXml() {
+			self.Imports["encoding/xml"] = true
+			break
+		}
+	}
+
+	// If any EnumRepr logs unknown strings, "log" is needed
+	for _, repr := range self.Enums {
+		if repr.UnmarshalsString() && !repr.IsStrict() && !repr.IsOpen() {
 			self.Imports["log"] = true
-			self.Imports["strings"] = true
 			break
 		}
 	}
 
-	// If any EnumRepr is `bitflag`, "strings" is needed
+	// If any open EnumRepr keeps unknown strings, "encoding/json" is needed
 	for _, repr := range self.Enums {
-		if repr.flags&bitflags == bitflags {
-			self.Imports["strings"] = true
+		if repr.UnmarshalsString() && repr.IsOpen() {
+			self.Imports["encoding/json"] = true
 			break
 		}
 	}
@@ -310,6 +911,7 @@ var enum_tmpl = `
 {{- range $enum := .}}
 {{- $intType := .GetIntType}}
 {{- $uniqField := .GetUniqueName}}
+{{- $rawField := .GetRawName}}
 {{- $variantType := printf "%sEnum" $enum.Name}}
 
 /*****************************
@@ -319,7 +921,14 @@ var enum_tmpl = `
 ******************************/
 
 {{$enum.DoDocs -}}
+{{if .IsOpen -}}
+type {{$variantType}} struct {
+	{{$uniqField}} {{$intType}}
+	{{$rawField}} string // JSON text of an unknown value
+}
+{{- else -}}
 type {{$variantType}} struct{ {{$uniqField}} {{$intType}} }
+{{- end}}
 
 var {{$enum.Name}} = struct {
 	{{- range $f := .Fields}}
@@ -330,7 +939,7 @@ var {{$enum.Name}} = struct {
 	{{.GetIterName}} [{{len .Fields}}]{{$variantType}}
 }{
 	{{- range $f := .Fields}}
-	{{$f.Name}}: {{$variantType}}{ {{$uniqField}}: {{$f.Value}} },
+	{{$f.Name}}: {{$variantType}}{ {{$uniqField}}: {{$f.ValueLit}} },
 	{{- end}}
 }
 
@@ -340,21 +949,126 @@ func init() {
 	}
 }
 
-// Value returns the numeric value of the variant as a {{$intType}}.
-func (self {{$variantType}}) Value() {{$intType}} {
+// Parse{{$enum.Name}} returns the variant whose string matches 's', ignoring
+// case. A *gEnum.UnknownVariantError is returned if there's no match.
+{{- if .IsBitflag}}
+{{- if .FlagSep}}
+// If there's no exact match, 's' is split using "{{.FlagSep}}" and each part
+// is matched individually.
+{{- else}}
+// If there's no exact match, 's' is split into the strings of variants, which
+// were joined without a separator. The longest string is matched first.
+{{- end}}
+{{- end}}
+func Parse{{$enum.Name}}(s string) ({{$variantType}}, error) {
+	switch strings.ToLower(s) {
+	{{range $f := .Fields -}}
+	case {{$f.CaseStrings}}:
+		return {{$enum.Name}}.{{$f.Name}}, nil
+	{{end -}}
+	}
+
+	{{if .IsBitflag -}}
+	var val {{$variantType}}
+
+	if len(s) == 0 {
+		return val, nil
+	}
+
+	{{if .FlagSep -}}
+	for _, part := range strings.Split(s, {{printf "%q" .FlagSep}}) {
+		switch strings.ToLower(part) {
+		{{range $f := .Fields -}}
+		case {{$f.CaseStrings}}:
+			val.{{$uniqField}} |= {{$f.ValueLit}}
+		{{end -}}
+		default:
+			return {{$variantType}}{}, &gEnum.UnknownVariantError{
+				Namespace: {{printf "%q" $enum.Name}}, Input: part,
+			}
+		}
+	}
+	{{- else -}}
+	for rest := strings.ToLower(s); len(rest) != 0; {
+		switch {
+		{{range .PrefixCases -}}
+		case strings.HasPrefix(rest, {{.Lit}}):
+			val.{{$uniqField}} |= {{.Value}}
+			rest = rest[len({{.Lit}}):]
+		{{end -}}
+		default:
+			return {{$variantType}}{}, &gEnum.UnknownVariantError{
+				Namespace: {{printf "%q" $enum.Name}}, Input: rest,
+			}
+		}
+	}
+	{{- end}}
+
+	return val, nil
+	{{- else -}}
+	return {{$variantType}}{}, &gEnum.UnknownVariantError{
+		Namespace: {{printf "%q" $enum.Name}}, Input: s,
+	}
+	{{- end}}
+}
+
+// {{$enum.Name}}FromName returns the variant with the given name, and 'false'
+// if there's no such variant. Names are case sensitive.
+func {{$enum.Name}}FromName(name string) ({{$variantType}}, bool) {
+	switch name {
+	{{range $f := .Fields -}}
+	case {{printf "%q" $f.Name}}:
+		return {{$enum.Name}}.{{$f.Name}}, true
+	{{end -}}
+	}
+	return {{$variantType}}{}, false
+}
+
+// {{$enum.Name}}FromValue returns the variant with the given value, and 'false'
+// if there's no such variant.
+{{- if .IsBitflag}}
+// Any combination of the values of the variants is accepted.
+{{- end}}
+func {{$enum.Name}}FromValue(v {{$intType}}) ({{$variantType}}, bool) {
+	{{if .IsBitflag -}}
+	const all = 0 {{- range $f := .Fields}} | {{$f.ValueLit}}{{end}}
+
+	if v&^all != 0 {
+		return {{$variantType}}{}, false
+	}
+	return {{$variantType}}{ {{$uniqField}}: v }, true
+	{{- else -}}
+	switch v {
+	{{range $f := .Fields -}}
+	case {{$f.ValueLit}}:
+		return {{$enum.Name}}.{{$f.Name}}, true
+	{{end -}}
+	}
+	return {{$variantType}}{}, false
+	{{- end}}
+}
+
+// {{.GetValueName}} returns the numeric value of the variant as a {{$intType}}.
+func (self {{$variantType}}) {{.GetValueName}}() {{$intType}} {
 	return self.{{$uniqField}}
 }
 
-// IntValue is the same as 'Value()', except that the value is cast to an 'int'.
+// IntValue is the same as '{{.GetValueName}}()', except that the value is cast to an 'int'.
 func (self {{$variantType}}) IntValue() int {
 	return int(self.{{$uniqField}})
 }
 
 // Name returns the name of the variant as a string.
 func (self {{$variantType}}) Name() string {
+	{{if .IsOpen -}}
+	if len(self.{{$rawField}}) != 0 {
+		return ""
+	}
+
+	{{end -}}
 	switch self.{{$uniqField}} {
 	{{range $f := .Fields -}}
-	case {{$f.Value}}:
+	case {{$f.ValueLit}}:
 		return {{printf "%q" $f.Name}}
 	{{end -}}
 	}
@@ -376,6 +1090,7 @@ func (self {{$variantType}}) Namespace() string {
 // there's no explicit default, and it has the zero value.
 func (self {{$variantType}}) IsDefault() bool {
 	return self.{{$uniqField}} == {{$enum.GetDefaultValue}}
+	{{- if .IsOpen}} && len(self.{{$rawField}}) == 0{{end}}
 }
 
 // IsZero returns true if the variant was designated as the default value, or if
@@ -389,12 +1104,18 @@ func (self {{$variantType}}) IsZero() bool {
 // its return value is as though 'Name()' had been called.
 {{if .IsBitflag -}}
 // If multiple bit values are assigned, the string values will be joined into a
-// single string using "{{.FlagSep}}" as a separator.
+// single string{{if .FlagSep}} using "{{.FlagSep}}" as a separator{{end}}.
 {{- end}}
 func (self {{$variantType}}) String() string {
+	{{if .IsOpen -}}
+	if len(self.{{$rawField}}) != 0 {
+		return self.unknownString()
+	}
+
+	{{end -}}
 	switch self.{{$uniqField}} {
 	{{range $f := .Fields -}}
-	case {{$f.Value}}:
+	case {{$f.ValueLit}}:
 		return {{printf "%q" $f.String}}
 	{{end -}}
   }
@@ -420,21 +1141,84 @@ func (self {{$variantType}}) String() string {
 // Description returns the description of the variant. If none has been set, its
 // return value is as though 'String()' had been called.
 func (self {{$variantType}}) Description() string {
+	{{if .IsOpen -}}
+	if len(self.{{$rawField}}) != 0 {
+		return ""
+	}
+
+	{{end -}}
   switch self.{{$uniqField}} {
 	{{range $f := .Fields -}}
-	case {{$f.Value}}:
+	case {{$f.ValueLit}}:
 		return {{printf "%q" $f.Description}}
 	{{end -}}
   }
   return ""
 }
 
+{{range $col := .Meta -}}
+// {{$col.Name}} returns the {{$col.Name}} metadata of the variant. If none has
+// been set, the zero value of {{$col.Type}} is returned.
+func (self {{$variantType}}) {{$col.Name}}() {{$col.Type}} {
+	{{if $enum.IsOpen -}}
+	if len(self.{{$rawField}}) != 0 {
+		return {{$col.Zero}}
+	}
+
+	{{end -}}
+	switch self.{{$uniqField}} {
+	{{range $f := $enum.Fields -}}
+	{{with $f.MetaValue $col.Name -}}
+	case {{$f.ValueLit}}:
+		return {{.}}
+	{{end -}}
+	{{end -}}
+	}
+	return {{$col.Zero}}
+}
+
+{{end -}}
+
+{{if not .IsBitflag -}}
+// Match calls the function given for the variant held by the receiver. There is
+// one parameter per variant, so adding a variant breaks every call site that
+// doesn't handle it. No function is called if the receiver is not a variant
+{{- if .IsOpen}}, or is an unknown value{{end}}.
+func (self {{$variantType}}) Match(
+	{{- range $f := .Fields}}
+	on{{$f.Name}} func(),
+	{{- end}}
+) {
+	{{if .IsOpen -}}
+	if len(self.{{$rawField}}) != 0 {
+		return
+	}
+
+	{{end -}}
+	switch self.{{$uniqField}} {
+	{{range $f := .Fields -}}
+	case {{$f.ValueLit}}:
+		on{{$f.Name}}()
+	{{end -}}
+	}
+}
+
+{{end -}}
 // JSONEncode implements part of Golific's JSONEncodable interface.
 func (self {{$variantType}}) JSONEncode(encoder *gJson.Encoder) bool {
+	{{if .IsOpen -}}
+	if len(self.{{$rawField}}) != 0 {
+		encoder.WriteRawString(self.{{$rawField}})
+		return true
+	}
+
+	{{end -}}
 	{{if $enum.JsonMarshalIsString -}}
 	encoder.EncodeString(self.String(), false)
-	{{- else -}}
+	{{- else if $enum.IsSigned -}}
 	encoder.EncodeInt(int64(self.{{$uniqField}}), false)
+	{{- else -}}
+	encoder.EncodeUint(uint64(self.{{$uniqField}}), false)
 	{{- end}}
   return true
 }
@@ -442,67 +1226,326 @@ func (self {{$variantType}}) JSONEncode(encoder *gJson.Encoder) bool {
 {{if $enum.DoJson -}}
 // JSON marshaling methods
 func (self {{$variantType}}) MarshalJSON() ([]byte, error) {
+	{{if .IsOpen -}}
+	if len(self.{{$rawField}}) != 0 {
+		return []byte(self.{{$rawField}}), nil
+	}
+
+	{{end -}}
 	{{if $enum.JsonMarshalIsString -}}
 	return []byte(strconv.Quote(self.String())), nil
 	{{- else -}}
-	return []byte(strconv.Itoa(int(self.{{$uniqField}}))), nil
+	return []byte({{.GetFormatValue}}), nil
 	{{- end}}
 }
 
-{{if $enum.JsonUnmarshalIsString -}}
+{{if .IsOpen -}}
+// UnmarshalJSON sets the receiver from a string or a number, so that unknown
+// values of either kind are kept.
+func (self *{{$variantType}}) UnmarshalJSON(b []byte) error {
+	if len(b) != 0 && b[0] == '"' {
+		var s, err = strconv.Unquote(string(b))
+		if err != nil {
+			return err
+		}
+
+		return self.setFromString(s)
+	}
+
+	return self.setFromNumber(string(b))
+}
+{{else if $enum.JsonUnmarshalIsString -}}
 func (self *{{$variantType}}) UnmarshalJSON(b []byte) error {
 	var s, err = strconv.Unquote(string(b))
 	if err != nil {
 		return err
 	}
 
-	if len(s) == 0 {
-		return nil
+	return self.setFromString(s)
+}
+{{else -}}
+func (self *{{$variantType}}) UnmarshalJSON(b []byte) error {
+	return self.setFromNumber(string(b))
+}
+{{- end}}
+
+// JSONDecode implements Golific's JSONDecodable interface.
+func (self *{{$variantType}}) JSONDecode(d *gJson.Decoder) error {
+	{{if .IsOpen -}}
+	if d.Peek() == gJson.StringToken {
+		var s, err = d.ReadString()
+		if err != nil {
+			return err
+		}
+
+		return self.setFromString(s)
 	}
 
-	switch strings.ToLower(s) {
-	{{range $f := .Fields -}}
-	case {{printf "%q" $f.LowerString}}:
-		self.{{$uniqField}} = {{$f.Value}}
+	var b, err = d.ReadNumber()
+	if err != nil {
+		return err
+	}
+
+	return self.setFromNumber(string(b))
+	{{- else if $enum.JsonUnmarshalIsString -}}
+	var s, err = d.ReadString()
+	if err != nil {
+		return err
+	}
+
+	return self.setFromString(s)
+	{{- else -}}
+	var b, err = d.ReadNumber()
+	if err != nil {
+		return err
+	}
+
+	return self.setFromNumber(string(b))
+	{{- end}}
+}
+{{- end}}
+
+
+{{- if $enum.DoText}}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (self {{$variantType}}) MarshalText() ([]byte, error) {
+	{{if $enum.TextMarshalIsString -}}
+	return []byte(self.String()), nil
+	{{- else -}}
+	return []byte(self.valueString()), nil
+	{{- end}}
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (self *{{$variantType}}) UnmarshalText(b []byte) error {
+	{{if $enum.TextUnmarshalIsString -}}
+	return self.setFromString(string(b))
+	{{- else -}}
+	return self.setFromNumber(string(b))
+	{{- end}}
+}
+{{- end}}
+
+{{- if $enum.DoXml}}
+
+// MarshalXML implements the xml.Marshaler interface.
+func (self {{$variantType}}) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	{{if $enum.XmlMarshalIsString -}}
+	return e.EncodeElement(self.String(), start)
+	{{- else -}}
+	return e.EncodeElement(self.valueString(), start)
+	{{- end}}
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface.
+func (self *{{$variantType}}) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var s string
+	if err := d.DecodeElement(&s, &start); err != nil {
+		return err
+	}
+
+	{{if $enum.XmlUnmarshalIsString -}}
+	return self.setFromString(s)
+	{{- else -}}
+	return self.setFromNumber(s)
+	{{- end}}
+}
+
+// MarshalXMLAttr implements the xml.MarshalerAttr interface.
+func (self {{$variantType}}) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	{{if $enum.XmlMarshalIsString -}}
+	return xml.Attr{Name: name, Value: self.String()}, nil
+	{{- else -}}
+	return xml.Attr{Name: name, Value: self.valueString()}, nil
+	{{- end}}
+}
+
+// UnmarshalXMLAttr implements the xml.UnmarshalerAttr interface.
+func (self *{{$variantType}}) UnmarshalXMLAttr(attr xml.Attr) error {
+	{{if $enum.XmlUnmarshalIsString -}}
+	return self.setFromString(attr.Value)
+	{{- else -}}
+	return self.setFromNumber(attr.Value)
+	{{- end}}
+}
+{{- end}}
+
+{{- if $enum.DoSql}}
+
+// Scan implements the sql.Scanner interface.
+func (self *{{$variantType}}) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*self = {{$variantType}}{}
 		return nil
+
+	case int64:
+		return self.setFromNumber(strconv.FormatInt(v, 10))
+
+	case []byte:
+		{{if $enum.SqlIsString -}}
+		return self.setFromString(string(v))
+		{{- else -}}
+		return self.setFromNumber(string(v))
+		{{- end}}
+
+	case string:
+		{{if $enum.SqlIsString -}}
+		return self.setFromString(v)
+		{{- else -}}
+		return self.setFromNumber(v)
+		{{- end}}
+	}
+
+	return fmt.Errorf("Unexpected type %T while scanning {{$variantType}}", src)
+}
+
+// Value implements the driver.Valuer interface.
+func (self {{$variantType}}) Value() (driver.Value, error) {
+	{{if $enum.SqlIsString -}}
+	return self.String(), nil
+	{{- else -}}
+	{{if .IsOpen -}}
+	if len(self.{{$rawField}}) != 0 {
+		return self.unknownString(), nil
+	}
+
 	{{end -}}
-	{{if not .IsBitflag -}}
-	default:
-		log.Printf("Unexpected value: %q while unmarshaling {{$variantType}}\n", s)
-	{{end -}}
+	{{if eq $intType "uint64" -}}
+	if int64(self.{{$uniqField}}) < 0 { // Drivers only need to accept an int64
+		return strconv.FormatUint(uint64(self.{{$uniqField}}), 10), nil
 	}
 
-	{{if .IsBitflag -}}
-	var val = 0
+	{{end -}}
+	return int64(self.{{$uniqField}}), nil
+	{{- end}}
+}
+{{- end}}
 
-	for _, part := range strings.Split(string(b), "{{.FlagSep}}") {
-		switch part {
-		{{range $f := .Fields -}}
-		case {{printf "%q" $f.String}}:
-			val |= {{$f.Value}}
-		{{end -}}
-  	default:
-			log.Printf("Unexpected value: %q while unmarshaling {{$variantType}}\n", part)
+{{- if $enum.UnmarshalsNumber}}
+
+// setFromNumber sets the receiver to the numeric value held by 's'.
+{{- if .IsOpen}}
+// An integer that is not the value of a variant{{if .IsBitflag}} or of a
+// combination of variants{{end}} is kept as an unknown value.
+{{- else if .IsStrict}}
+// A *gEnum.UnknownVariantError is returned if 's' is not the value of a
+// variant{{if .IsBitflag}} or of a combination of variants{{end}}.
+{{- end}}
+func (self *{{$variantType}}) setFromNumber(s string) error {
+	{{if .IsOpen -}}
+	var n, err = strconv.{{.GetParseFunc}}(s, 10, {{.GetIntBits}})
+	if err == nil {
+		if v, ok := {{$enum.Name}}FromValue({{$intType}}(n)); ok {
+			*self = v
+			return nil
 		}
+
+	} else if _, err = strconv.ParseInt(s, 10, 64); err != nil &&
+		err.(*strconv.NumError).Err != strconv.ErrRange {
+		return err // not an integer
 	}
 
-	self.{{$uniqField}} = {{$intType}}(val)
-	{{end -}}
+	*self = {{$variantType}}{ {{$rawField}}: s }
+	{{- else if .IsStrict -}}
+	var n, err = strconv.{{.GetParseFunc}}(s, 10, {{.GetIntBits}})
+	if err != nil {
+		return &gEnum.UnknownVariantError{Namespace: {{printf "%q" $enum.Name}}, Input: s}
+	}
 
+	v, ok := {{$enum.Name}}FromValue({{$intType}}(n))
+	if !ok {
+		return &gEnum.UnknownVariantError{Namespace: {{printf "%q" $enum.Name}}, Input: s}
+	}
+	*self = v
+	{{- else -}}
+	var n, err = strconv.{{.GetParseFunc}}(s, 10, 64)
+	if err != nil {
+		return err
+	}
+	self.{{$uniqField}} = {{$intType}}(n)
+	{{- end}}
 	return nil
 }
-{{else -}}
-func (self *{{$variantType}}) UnmarshalJSON(b []byte) error {
-	var n, err = strconv.ParseUint(string(b), 10, 64)
+{{- end}}
+
+{{- if $enum.UnmarshalsString}}
+
+// setFromString sets the receiver to the variant parsed from 's' by
+// Parse{{$enum.Name}}.
+{{- if .IsStrict}} Its error is returned if there's no match.
+{{- else}} An empty string leaves the receiver unchanged.
+{{- if .IsOpen}}
+// If there's no match, 's' is kept as an unknown value.
+{{- else}}
+// If there's no match, the error is logged and the receiver is unchanged.
+{{- end}}
+{{- end}}
+func (self *{{$variantType}}) setFromString(s string) error {
+	{{if not .IsStrict -}}
+	if len(s) == 0 {
+		return nil
+	}
+
+	{{end -}}
+	v, err := Parse{{$enum.Name}}(s)
 	if err != nil {
+		{{if .IsStrict -}}
 		return err
+		{{- else if .IsOpen -}}
+		raw, _ := json.Marshal(s)
+		*self = {{$variantType}}{ {{$rawField}}: string(raw) }
+		return nil
+		{{- else -}}
+		log.Printf("%s while unmarshaling {{$variantType}}\n", err)
+		return nil
+		{{- end}}
 	}
-	self.{{$uniqField}} = {{$intType}}(n)
+	*self = v
 	return nil
 }
 {{- end}}
+
+{{- if or .DoText .DoXml}}
+
+// valueString returns the numeric value of the variant as a string.
+{{- if .IsOpen}}
+// An unknown value is returned as though 'String()' had been called.
+{{- end}}
+func (self {{$variantType}}) valueString() string {
+	{{if .IsOpen -}}
+	if len(self.{{$rawField}}) != 0 {
+		return self.unknownString()
+	}
+
+	{{end -}}
+	return {{.GetFormatValue}}
+}
 {{- end}}
 
+{{- if .IsOpen}}
+
+// IsUnknown returns 'true' if the receiver holds an unknown value, which is a
+// string or number that matched no variant when it was unmarshaled.
+func (self {{$variantType}}) IsUnknown() bool {
+	return len(self.{{$rawField}}) != 0
+}
+
+// Unknown returns the unknown value held by the receiver as the JSON text it
+// will be marshaled as, or an empty string if it holds no unknown value.
+func (self {{$variantType}}) Unknown() string {
+	return self.{{$rawField}}
+}
+
+// unknownString returns the unknown value, without quotes if it's a string.
+func (self {{$variantType}}) unknownString() string {
+	if s, err := strconv.Unquote(self.{{$rawField}}); err == nil {
+		return s
+	}
+	return self.{{$rawField}}
+}
+{{- end}}
 
 {{- if .IsBitflag}}
 // Bitflag enum methods
@@ -0,0 +1,14 @@
+package gEnum
+
+import "fmt"
+
+// UnknownVariantError is returned when a string or number matches no variant of
+// an enum.
+type UnknownVariantError struct {
+	Namespace string // The namespace of the enum, like "Animal"
+	Input     string // The string or number that was not matched
+}
+
+func (e *UnknownVariantError) Error() string {
+	return fmt.Sprintf("%q is not a variant of %s", e.Input, e.Namespace)
+}
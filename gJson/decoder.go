@@ -0,0 +1,601 @@
+package gJson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+/*
+Decoder reads JSON values from a byte slice in a single pass, without
+reflection. Generated UnmarshalJSON methods use it to decode each field as its
+key is found.
+*/
+type Decoder struct {
+	b      []byte
+	pos    int
+	keyBuf []byte // Unescaped object keys
+	strBuf []byte // Unescaped string values
+}
+
+type TokenKind uint8
+
+const (
+	EndToken TokenKind = iota // The end of the input
+	ObjectStartToken
+	ObjectEndToken
+	ArrayStartToken
+	ArrayEndToken
+	StringToken
+	NumberToken
+	TrueToken
+	FalseToken
+	NullToken
+)
+
+/*
+Token is a single piece of JSON. The Value of a StringToken is the unescaped
+string, and that of a NumberToken the text of the number. It's only valid until
+the decoder is used again.
+*/
+type Token struct {
+	Kind  TokenKind
+	Value []byte
+}
+
+type SyntaxError struct {
+	Offset int
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at offset %d", e.Msg, e.Offset)
+}
+
+func NewDecoder(b []byte) *Decoder {
+	return &Decoder{b: b}
+}
+
+// Reset makes the decoder read from `b`, keeping its buffers.
+func (d *Decoder) Reset(b []byte) {
+	d.b, d.pos = b, 0
+}
+
+func (d *Decoder) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{Offset: d.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (d *Decoder) skipSpace() {
+	for d.pos < len(d.b) {
+		switch d.b[d.pos] {
+		case ' ', '\t', '\n', '\r':
+			d.pos++
+		default:
+			return
+		}
+	}
+}
+
+// Returns the next non-space byte without consuming it, or 0 at the end.
+func (d *Decoder) peek() byte {
+	d.skipSpace()
+	if d.pos < len(d.b) {
+		return d.b[d.pos]
+	}
+	return 0
+}
+
+func (d *Decoder) expect(c byte) error {
+	if d.peek() != c {
+		return d.unexpected()
+	}
+	d.pos++
+	return nil
+}
+
+func (d *Decoder) unexpected() error {
+	if d.pos >= len(d.b) {
+		return d.errorf("Unexpected end of JSON input")
+	}
+	return d.errorf("Unexpected character %q", d.b[d.pos])
+}
+
+func (d *Decoder) readLiteral(lit string) error {
+	d.skipSpace()
+	if !bytes.HasPrefix(d.b[d.pos:], []byte(lit)) {
+		return d.unexpected()
+	}
+	d.pos += len(lit)
+	return nil
+}
+
+// Peek returns the kind of the next token without consuming it. Commas and
+// colons are skipped, as with NextToken.
+func (d *Decoder) Peek() TokenKind {
+	if c := d.peek(); c == ',' || c == ':' {
+		d.pos++
+	}
+
+	switch d.peek() {
+	case 0:
+		return EndToken
+	case '{':
+		return ObjectStartToken
+	case '}':
+		return ObjectEndToken
+	case '[':
+		return ArrayStartToken
+	case ']':
+		return ArrayEndToken
+	case '"':
+		return StringToken
+	case 't':
+		return TrueToken
+	case 'f':
+		return FalseToken
+	case 'n':
+		return NullToken
+	}
+	return NumberToken
+}
+
+/*
+NextToken consumes and returns the next token. The commas and colons between
+values are skipped, but their placement isn't checked; ReadObject and
+ReadArray should be used where the structure matters.
+*/
+func (d *Decoder) NextToken() (tok Token, err error) {
+	switch tok.Kind = d.Peek(); tok.Kind {
+	case ObjectStartToken, ObjectEndToken, ArrayStartToken, ArrayEndToken:
+		d.pos++
+	case StringToken:
+		tok.Value, err = d.readString(&d.strBuf)
+	case NumberToken:
+		tok.Value, err = d.readNumber()
+	case TrueToken:
+		err = d.readLiteral("true")
+	case FalseToken:
+		err = d.readLiteral("false")
+	case NullToken:
+		err = d.readLiteral("null")
+	}
+	return tok, err
+}
+
+// End returns an error if anything but space follows the decoded value.
+func (d *Decoder) End() error {
+	if d.peek() != 0 {
+		return d.unexpected()
+	}
+	return nil
+}
+
+// ReadNull consumes a `null` and returns `true` if it's the next value.
+func (d *Decoder) ReadNull() bool {
+	if d.peek() == 'n' && d.readLiteral("null") == nil {
+		return true
+	}
+	return false
+}
+
+/*
+ReadObject reads an object, calling `fn` with each key. The function must
+consume the key's value. The key is only valid until `fn` returns.
+*/
+func (d *Decoder) ReadObject(fn func(key []byte) error) error {
+	if err := d.expect('{'); err != nil {
+		return err
+	}
+
+	if d.peek() == '}' {
+		d.pos++
+		return nil
+	}
+
+	for {
+		key, err := d.readString(&d.keyBuf)
+		if err != nil {
+			return err
+		}
+
+		if err = d.expect(':'); err != nil {
+			return err
+		}
+
+		if err = fn(key); err != nil {
+			return err
+		}
+
+		switch d.peek() {
+		case ',':
+			d.pos++
+		case '}':
+			d.pos++
+			return nil
+		default:
+			return d.unexpected()
+		}
+	}
+}
+
+// ReadArray reads an array, calling `fn` for each element. The function must
+// consume the element.
+func (d *Decoder) ReadArray(fn func() error) error {
+	if err := d.expect('['); err != nil {
+		return err
+	}
+
+	if d.peek() == ']' {
+		d.pos++
+		return nil
+	}
+
+	for {
+		if err := fn(); err != nil {
+			return err
+		}
+
+		switch d.peek() {
+		case ',':
+			d.pos++
+		case ']':
+			d.pos++
+			return nil
+		default:
+			return d.unexpected()
+		}
+	}
+}
+
+// KeyMatches compares an object key with the JSON name of a field.
+func KeyMatches(key []byte, name string, ignoreCase bool) bool {
+	if ignoreCase {
+		return bytes.EqualFold(key, []byte(name))
+	}
+	return string(key) == name
+}
+
+// ReadString reads a string. Use ReadStringBytes to avoid the allocation.
+func (d *Decoder) ReadString() (string, error) {
+	b, err := d.readString(&d.strBuf)
+	return string(b), err
+}
+
+// ReadStringBytes reads a string. The bytes are only valid until the decoder is
+// used again.
+func (d *Decoder) ReadStringBytes() ([]byte, error) {
+	return d.readString(&d.strBuf)
+}
+
+/*
+Reads a string, unescaping it into `buf`, which keeps the memory for the next
+string. If there's nothing to unescape, the bytes of the input are returned
+instead, without copying.
+*/
+func (d *Decoder) readString(buf *[]byte) ([]byte, error) {
+	if err := d.expect('"'); err != nil {
+		return nil, err
+	}
+
+	var start = d.pos
+
+	for d.pos < len(d.b) {
+		switch c := d.b[d.pos]; {
+		case c == '"':
+			d.pos++
+			return d.b[start : d.pos-1], nil
+		case c == '\\':
+			b, err := d.readEscapedString(append((*buf)[:0], d.b[start:d.pos]...))
+			*buf = b
+			return b, err
+		case c < 0x20:
+			return nil, d.errorf("Invalid character %q in string", c)
+		default:
+			d.pos++
+		}
+	}
+	return nil, d.unexpected()
+}
+
+func (d *Decoder) readEscapedString(buf []byte) ([]byte, error) {
+	for d.pos < len(d.b) {
+		var c = d.b[d.pos]
+
+		switch {
+		case c == '"':
+			d.pos++
+			return buf, nil
+
+		case c < 0x20:
+			return buf, d.errorf("Invalid character %q in string", c)
+
+		case c != '\\':
+			buf = append(buf, c)
+			d.pos++
+			continue
+		}
+
+		if d.pos++; d.pos >= len(d.b) {
+			break
+		}
+
+		switch c = d.b[d.pos]; c {
+		case '"', '\\', '/':
+			buf = append(buf, c)
+		case 'b':
+			buf = append(buf, '\b')
+		case 'f':
+			buf = append(buf, '\f')
+		case 'n':
+			buf = append(buf, '\n')
+		case 'r':
+			buf = append(buf, '\r')
+		case 't':
+			buf = append(buf, '\t')
+
+		case 'u':
+			r, ok := d.readHex4(d.pos + 1)
+			if !ok {
+				return buf, d.errorf("Invalid unicode escape")
+			}
+			d.pos += 4
+
+			if utf16.IsSurrogate(r) {
+				// A surrogate pair is written as two escapes
+				var r2 = utf8.RuneError
+				if d.pos+2 < len(d.b) && d.b[d.pos+1] == '\\' && d.b[d.pos+2] == 'u' {
+					if lo, ok := d.readHex4(d.pos + 3); ok {
+						if r2 = utf16.DecodeRune(r, lo); r2 != utf8.RuneError {
+							d.pos += 6
+						}
+					}
+				}
+				r = r2
+			}
+			buf = utf8.AppendRune(buf, r)
+
+		default:
+			return buf, d.errorf("Invalid escape %q in string", c)
+		}
+		d.pos++
+	}
+	return buf, d.unexpected()
+}
+
+func (d *Decoder) readHex4(pos int) (rune, bool) {
+	if pos+4 > len(d.b) {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(string(d.b[pos:pos+4]), 16, 32)
+	return rune(n), err == nil
+}
+
+func (d *Decoder) ReadBool() (bool, error) {
+	switch d.peek() {
+	case 't':
+		return true, d.readLiteral("true")
+	case 'f':
+		return false, d.readLiteral("false")
+	}
+	return false, d.unexpected()
+}
+
+// ReadNumber returns the text of the next number. The bytes aren't copied.
+func (d *Decoder) ReadNumber() ([]byte, error) {
+	return d.readNumber()
+}
+
+func (d *Decoder) readNumber() ([]byte, error) {
+	d.skipSpace()
+
+	var start = d.pos
+	var digits = func() bool {
+		var n = d.pos
+		for d.pos < len(d.b) && '0' <= d.b[d.pos] && d.b[d.pos] <= '9' {
+			d.pos++
+		}
+		return d.pos > n
+	}
+
+	if d.pos < len(d.b) && d.b[d.pos] == '-' {
+		d.pos++
+	}
+	if d.pos < len(d.b) && d.b[d.pos] == '0' {
+		d.pos++ // A leading zero is the whole integer part
+	} else if !digits() {
+		return nil, d.unexpected()
+	}
+	if d.pos < len(d.b) && d.b[d.pos] == '.' {
+		d.pos++
+		if !digits() {
+			return nil, d.unexpected()
+		}
+	}
+	if d.pos < len(d.b) && (d.b[d.pos] == 'e' || d.b[d.pos] == 'E') {
+		d.pos++
+		if d.pos < len(d.b) && (d.b[d.pos] == '+' || d.b[d.pos] == '-') {
+			d.pos++
+		}
+		if !digits() {
+			return nil, d.unexpected()
+		}
+	}
+	return d.b[start:d.pos], nil
+}
+
+// ReadInt reads an integer that fits in a signed integer of the given size.
+func (d *Decoder) ReadInt(bitSize int) (int64, error) {
+	b, err := d.readNumber()
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(string(b), 10, bitSize)
+}
+
+// ReadUint reads an integer that fits in an unsigned integer of the given size.
+func (d *Decoder) ReadUint(bitSize int) (uint64, error) {
+	b, err := d.readNumber()
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(string(b), 10, bitSize)
+}
+
+// ReadFloat reads a number that fits in a float of the given size.
+func (d *Decoder) ReadFloat(bitSize int) (float64, error) {
+	b, err := d.readNumber()
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(string(b), bitSize)
+}
+
+// Skip consumes the next value, whatever its type.
+func (d *Decoder) Skip() error {
+	switch d.peek() {
+	case '{':
+		return d.ReadObject(func([]byte) error { return d.Skip() })
+	case '[':
+		return d.ReadArray(d.Skip)
+	case '"':
+		_, err := d.readString(&d.strBuf)
+		return err
+	case 't':
+		return d.readLiteral("true")
+	case 'f':
+		return d.readLiteral("false")
+	case 'n':
+		return d.readLiteral("null")
+	}
+
+	_, err := d.readNumber()
+	return err
+}
+
+// PeekRaw returns the bytes of the next value without consuming it.
+func (d *Decoder) PeekRaw() ([]byte, error) {
+	var pos = d.pos
+	raw, err := d.ReadRaw()
+	d.pos = pos
+	return raw, err
+}
+
+// ReadRaw consumes the next value and returns its bytes. They aren't copied.
+func (d *Decoder) ReadRaw() ([]byte, error) {
+	d.skipSpace()
+	var start = d.pos
+	err := d.Skip()
+	return d.b[start:d.pos], err
+}
+
+/*
+Decode reads the next value into `v`, which must be a pointer. A JSONDecodable
+decodes itself. Pointers to strings, bools and numbers are decoded directly,
+and a `null` leaves them unchanged, as does an error, such as a number that's
+out of range. A json.Unmarshaler receives the bytes of the value. Anything else
+falls back to json.Unmarshal.
+*/
+func (d *Decoder) Decode(v interface{}) (err error) {
+	switch v.(type) {
+	case *string, *bool, *int, *int64, *int32, *int16, *int8,
+		*uint, *uint64, *uint32, *uint16, *uint8, *float64, *float32:
+		if d.ReadNull() {
+			return nil
+		}
+	}
+
+	switch p := v.(type) {
+	case JSONDecodable:
+		err = p.JSONDecode(d)
+
+	case *string:
+		var s string
+		if s, err = d.ReadString(); err == nil {
+			*p = s
+		}
+	case *bool:
+		var b bool
+		if b, err = d.ReadBool(); err == nil {
+			*p = b
+		}
+
+	case *int:
+		var n int64
+		if n, err = d.ReadInt(strconv.IntSize); err == nil {
+			*p = int(n)
+		}
+	case *int64:
+		var n int64
+		if n, err = d.ReadInt(64); err == nil {
+			*p = n
+		}
+	case *int32:
+		var n int64
+		if n, err = d.ReadInt(32); err == nil {
+			*p = int32(n)
+		}
+	case *int16:
+		var n int64
+		if n, err = d.ReadInt(16); err == nil {
+			*p = int16(n)
+		}
+	case *int8:
+		var n int64
+		if n, err = d.ReadInt(8); err == nil {
+			*p = int8(n)
+		}
+
+	case *uint:
+		var n uint64
+		if n, err = d.ReadUint(strconv.IntSize); err == nil {
+			*p = uint(n)
+		}
+	case *uint64:
+		var n uint64
+		if n, err = d.ReadUint(64); err == nil {
+			*p = n
+		}
+	case *uint32:
+		var n uint64
+		if n, err = d.ReadUint(32); err == nil {
+			*p = uint32(n)
+		}
+	case *uint16:
+		var n uint64
+		if n, err = d.ReadUint(16); err == nil {
+			*p = uint16(n)
+		}
+	case *uint8:
+		var n uint64
+		if n, err = d.ReadUint(8); err == nil {
+			*p = uint8(n)
+		}
+
+	case *float64:
+		var n float64
+		if n, err = d.ReadFloat(64); err == nil {
+			*p = n
+		}
+	case *float32:
+		var n float64
+		if n, err = d.ReadFloat(32); err == nil {
+			*p = float32(n)
+		}
+
+	case json.Unmarshaler:
+		var raw []byte
+		if raw, err = d.ReadRaw(); err == nil {
+			err = p.UnmarshalJSON(raw)
+		}
+
+	default:
+		var raw []byte
+		if raw, err = d.ReadRaw(); err == nil {
+			err = json.Unmarshal(raw, v)
+		}
+	}
+	return err
+}
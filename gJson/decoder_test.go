@@ -0,0 +1,274 @@
+package gJson
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSkipMalformed(t *testing.T) {
+	for _, input := range []string{
+		``,
+		`{`,
+		`[`,
+		`{"a":1,}`,
+		`{"a" 1}`,
+		`{"a":}`,
+		`{1:2}`,
+		`[1,]`,
+		`[1 2]`,
+		`[1}`,
+		`{"a":1]`,
+		`tru`,
+		`nul`,
+		`falsy`,
+		`"abc`,
+		`"a` + "\n" + `b"`,
+		`"\x"`,
+		`"\u12"`,
+		`"\u12zz"`,
+		`"abc\`,
+		`01`,
+		`-`,
+		`-a`,
+		`1.`,
+		`1.e5`,
+		`1e`,
+		`1e+`,
+		`+1`,
+		`.5`,
+		`{"a":1}}`,
+		`[1] [2]`,
+	} {
+		var d = NewDecoder([]byte(input))
+
+		if err := d.Skip(); err == nil {
+			if err = d.End(); err == nil {
+				t.Errorf("%q: expected an error", input)
+			}
+		}
+	}
+}
+
+func TestSkipValid(t *testing.T) {
+	for _, input := range []string{
+		`0`,
+		`-0.5e+10`,
+		`1E-2`,
+		`""`,
+		`"\"\\\/\b\f\n\r\t\u00e9"`,
+		` { "a" : [ 1 , true , false , null , { } , [ ] ] , "b" : "x" } `,
+		`[[[[]]]]`,
+	} {
+		var d = NewDecoder([]byte(input))
+
+		if err := d.Skip(); err != nil {
+			t.Errorf("%q: %s", input, err)
+		} else if err = d.End(); err != nil {
+			t.Errorf("%q: %s", input, err)
+		}
+	}
+}
+
+func TestReadStringEscapes(t *testing.T) {
+	for _, test := range []struct {
+		input, expect string
+	}{
+		{`"plain"`, "plain"},
+		{`"a\"b"`, `a"b`},
+		{`"\\\/"`, `\/`},
+		{`"\b\f\n\r\t"`, "\b\f\n\r\t"},
+		{`"\u0041\u00e9\u4E16"`, "Aé世"},
+		{`"caf\u00E9\u0021"`, "café!"},
+		{`"\ud83d\ude00"`, "\U0001F600"},
+		{`"x\ud83d\ude00y"`, "x\U0001F600y"},
+		{`"\ud83d"`, "\uFFFD"},
+		{`"\ud83dx"`, "\uFFFDx"},
+		{`"\ud83d\u0041"`, "\uFFFDA"},
+		{`"\ude00\ud83d"`, "\uFFFD\uFFFD"},
+		{`"\ud83d\ud83d\ude00"`, "\uFFFD\U0001F600"},
+	} {
+		var d = NewDecoder([]byte(test.input))
+
+		s, err := d.ReadString()
+		if err != nil {
+			t.Errorf("%s: %s", test.input, err)
+			continue
+		}
+		if s != test.expect {
+			t.Errorf("%s: expected %q; found %q", test.input, test.expect, s)
+		}
+		if err = d.End(); err != nil {
+			t.Errorf("%s: %s", test.input, err)
+		}
+	}
+}
+
+func TestReadObjectKeys(t *testing.T) {
+	var d = NewDecoder([]byte(`{"a\u0062":1, "c":"d", "e":{"f":2}}`))
+	var keys []string
+
+	var err = d.ReadObject(func(key []byte) error {
+		keys = append(keys, string(key))
+		return d.Skip()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var expect = []string{"ab", "c", "e"}
+
+	if len(keys) != len(expect) {
+		t.Fatalf("expected keys %q; found %q", expect, keys)
+	}
+	for i := range keys {
+		if keys[i] != expect[i] {
+			t.Errorf("expected keys %q; found %q", expect, keys)
+		}
+	}
+}
+
+func TestDecodeOutOfRange(t *testing.T) {
+	var (
+		i8  int8    = 7
+		i32 int32   = 7
+		i64 int64   = 7
+		u8  uint8   = 7
+		u64 uint64  = 7
+		f32 float32 = 7
+		s           = "unchanged"
+		b           = true
+	)
+
+	for _, test := range []struct {
+		input string
+		ptr   interface{}
+		check func() bool
+	}{
+		{`300`, &i8, func() bool { return i8 == 7 }},
+		{`-129`, &i8, func() bool { return i8 == 7 }},
+		{`1.5`, &i8, func() bool { return i8 == 7 }},
+		{`2147483648`, &i32, func() bool { return i32 == 7 }},
+		{`9223372036854775808`, &i64, func() bool { return i64 == 7 }},
+		{`256`, &u8, func() bool { return u8 == 7 }},
+		{`-1`, &u8, func() bool { return u8 == 7 }},
+		{`18446744073709551616`, &u64, func() bool { return u64 == 7 }},
+		{`1e40`, &f32, func() bool { return f32 == 7 }},
+		{`"x`, &s, func() bool { return s == "unchanged" }},
+		{`1`, &s, func() bool { return s == "unchanged" }},
+		{`"true"`, &b, func() bool { return b }},
+	} {
+		var d = NewDecoder([]byte(test.input))
+
+		if err := d.Decode(test.ptr); err == nil {
+			t.Errorf("%s: expected an error", test.input)
+		}
+		if !test.check() {
+			t.Errorf("%s: the value was changed", test.input)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	var (
+		i8  int8    = 7
+		u64 uint64  = 7
+		f32 float32 = 7
+		s           = "unchanged"
+		m   map[string]int
+	)
+
+	for _, test := range []struct {
+		input string
+		ptr   interface{}
+		check func() bool
+	}{
+		{`-128`, &i8, func() bool { return i8 == -128 }},
+		{`null`, &i8, func() bool { return i8 == -128 }},
+		{`18446744073709551615`, &u64, func() bool { return u64 == math.MaxUint64 }},
+		{`0.25`, &f32, func() bool { return f32 == 0.25 }},
+		{`null`, &s, func() bool { return s == "unchanged" }},
+		{`"\u00e9"`, &s, func() bool { return s == "é" }},
+		{`{"a":1}`, &m, func() bool { return m["a"] == 1 }},
+		{`null`, &m, func() bool { return m == nil }},
+	} {
+		var d = NewDecoder([]byte(test.input))
+
+		if err := d.Decode(test.ptr); err != nil {
+			t.Errorf("%s: %s", test.input, err)
+		} else if !test.check() {
+			t.Errorf("%s: unexpected value %v", test.input, test.ptr)
+		}
+	}
+}
+
+func TestSkipAndPeekRaw(t *testing.T) {
+	var d = NewDecoder([]byte(` {"a":[1,{"b":null}],"c":"x\"}"} 5 [] `))
+
+	raw, err := d.PeekRaw()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(raw) != `{"a":[1,{"b":null}],"c":"x\"}"}` {
+		t.Errorf("PeekRaw returned %s", raw)
+	}
+
+	// PeekRaw consumes nothing, so the same value is read again
+	if raw2, err := d.ReadRaw(); err != nil || string(raw2) != string(raw) {
+		t.Errorf("ReadRaw returned %s, %v", raw2, err)
+	}
+
+	if n, err := d.ReadInt(64); err != nil || n != 5 {
+		t.Errorf("ReadInt returned %d, %v", n, err)
+	}
+
+	if err := d.Skip(); err != nil {
+		t.Error(err)
+	}
+	if err := d.End(); err != nil {
+		t.Error(err)
+	}
+
+	// A failed PeekRaw also leaves the position unchanged
+	d.Reset([]byte(`[1,2`))
+
+	if _, err := d.PeekRaw(); err == nil {
+		t.Error("PeekRaw: expected an error")
+	}
+	if tok, err := d.NextToken(); err != nil || tok.Kind != ArrayStartToken {
+		t.Errorf("NextToken returned %v, %v", tok, err)
+	}
+}
+
+func TestNextToken(t *testing.T) {
+	var d = NewDecoder([]byte(`{"a":[-1.5,true,false,null,"s"]}`))
+	var kinds []TokenKind
+	var values []string
+
+	for {
+		tok, err := d.NextToken()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok.Kind == EndToken {
+			break
+		}
+		kinds = append(kinds, tok.Kind)
+		values = append(values, string(tok.Value))
+	}
+
+	var expectKinds = []TokenKind{
+		ObjectStartToken, StringToken, ArrayStartToken, NumberToken, TrueToken,
+		FalseToken, NullToken, StringToken, ArrayEndToken, ObjectEndToken,
+	}
+	var expectValues = []string{"", "a", "", "-1.5", "", "", "", "s", "", ""}
+
+	if len(kinds) != len(expectKinds) {
+		t.Fatalf("expected %v; found %v", expectKinds, kinds)
+	}
+	for i := range kinds {
+		if kinds[i] != expectKinds[i] || values[i] != expectValues[i] {
+			t.Errorf("token %d: expected %v %q; found %v %q",
+				i, expectKinds[i], expectValues[i], kinds[i], values[i])
+		}
+	}
+}
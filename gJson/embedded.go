@@ -0,0 +1,262 @@
+package gJson
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// The JSON name of a field promoted from an embedded struct.
+type embeddedKey struct {
+	name   string
+	depth  int  // 1 for the fields of the embedded struct itself
+	tagged bool // The name was given by a json tag
+	field  int  // Index of the embedded field it was promoted through, or -1
+}
+
+var embeddedKeys sync.Map // reflect.Type -> []embeddedKey
+
+var jsonDecodableType = reflect.TypeOf((*JSONDecodable)(nil)).Elem()
+
+/*
+SplitEmbedded divides the keys of the JSON object `raw` among the embedded
+fields of a struct, which are given as pointers, and returns an object for each
+field holding its keys. The object is nil for a field that has no key in `raw`,
+so that an embedded pointer need only be allocated when one of its keys is
+present.
+
+Keys are resolved as Go resolves the names of fields. The names in `outer`,
+which are the JSON names of the struct's own fields, shadow every embedded
+field of the same name, and the keys matching them (ignoring case if
+`ignoreCase`) are given to no field. Of the embedded fields sharing a name, the
+least deeply embedded one wins, then the only one with a json tag, and if that
+leaves several, none of them gets the key. Keys are matched exactly first, then
+ignoring case.
+
+A field whose type is not a struct has no known keys, so it's given every key
+that matches no name of another field.
+*/
+func SplitEmbedded(raw []byte, outer []string, ignoreCase bool,
+	fields ...interface{}) ([][]byte, error) {
+
+	var owners []embeddedKey // The field that wins each name, in field order
+	var catchAll []int
+
+	for i, f := range fields {
+		keys, ok := keysOf(reflect.TypeOf(f).Elem())
+		if !ok {
+			catchAll = append(catchAll, i)
+			continue
+		}
+
+		for _, key := range keys {
+			key.field = i
+			owners = append(owners, key)
+		}
+	}
+
+	owners = dominantKeys(owners, outer)
+
+	var parts = make([]*Encoder, len(fields))
+
+	var add = func(i int, key, value []byte) {
+		if parts[i] == nil {
+			parts[i] = new(Encoder)
+			parts[i].WriteRawByte('{')
+		} else {
+			parts[i].WriteRawByte(',')
+		}
+		parts[i].EncodeString(string(key), false)
+		parts[i].WriteRawByte(':')
+		parts[i].WriteRaw(value)
+	}
+
+	var d = NewDecoder(raw)
+
+	var err = d.ReadObject(func(key []byte) error {
+		value, err := d.ReadRaw()
+		if err != nil {
+			return err
+		}
+
+		for _, name := range outer {
+			if KeyMatches(key, name, ignoreCase) {
+				return nil
+			}
+		}
+
+		if i, ok := ownerOf(owners, key); ok {
+			if i != -1 {
+				add(i, key, value)
+			}
+			return nil
+		}
+
+		for _, i := range catchAll {
+			add(i, key, value)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var res = make([][]byte, len(fields))
+
+	for i, p := range parts {
+		if p != nil {
+			p.WriteRawByte('}')
+			res[i] = p.Bytes()
+		}
+	}
+	return res, nil
+}
+
+// Returns the index of the field that gets the key, which is -1 if its name is
+// shadowed or ambiguous, and `false` if it matches no name.
+func ownerOf(owners []embeddedKey, key []byte) (int, bool) {
+	for _, o := range owners {
+		if string(key) == o.name {
+			return o.field, true
+		}
+	}
+	for _, o := range owners {
+		if bytes.EqualFold(key, []byte(o.name)) {
+			return o.field, true
+		}
+	}
+	return -1, false
+}
+
+// Returns the key that wins each name. The field of those shadowed by `outer`
+// or that are ambiguous is -1.
+func dominantKeys(keys []embeddedKey, outer []string) []embeddedKey {
+	var byName = make(map[string][]embeddedKey, len(keys))
+	var names []string
+
+	for _, key := range keys {
+		if _, ok := byName[key.name]; !ok {
+			names = append(names, key.name)
+		}
+		byName[key.name] = append(byName[key.name], key)
+	}
+
+	var shadowed = make(map[string]bool, len(outer))
+	for _, name := range outer {
+		shadowed[name] = true
+	}
+
+	var res []embeddedKey
+
+	for _, name := range names {
+		if shadowed[name] {
+			res = append(res, embeddedKey{name: name, field: -1})
+			continue
+		}
+
+		var best []embeddedKey
+
+		for _, key := range byName[name] {
+			if len(best) == 0 || key.depth < best[0].depth {
+				best = append(best[:0], key)
+			} else if key.depth == best[0].depth {
+				best = append(best, key)
+			}
+		}
+
+		if len(best) > 1 {
+			var tagged []embeddedKey
+			for _, key := range best {
+				if key.tagged {
+					tagged = append(tagged, key)
+				}
+			}
+			best = tagged
+		}
+
+		if len(best) == 1 {
+			res = append(res, best[0])
+		} else {
+			res = append(res, embeddedKey{name: name, field: -1})
+		}
+	}
+	return res
+}
+
+// Returns the JSON names of the fields of the struct, or of the struct that a
+// pointer type points to, including those promoted from its embedded structs.
+// Returns `false` if it isn't a struct.
+func keysOf(t reflect.Type) ([]embeddedKey, bool) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, false
+	}
+
+	if keys, ok := embeddedKeys.Load(t); ok {
+		return keys.([]embeddedKey), true
+	}
+
+	var keys = collectKeys(t, 1, nil, map[reflect.Type]bool{})
+
+	embeddedKeys.Store(t, keys)
+	return keys, true
+}
+
+/*
+Appends the JSON names of the fields of the struct at the depth. A JSONDecodable
+struct is taken to be generated by Golific, which also decodes unexported fields
+that have a json tag. Other structs are decoded by encoding/json, which ignores
+them.
+*/
+func collectKeys(t reflect.Type, depth int, keys []embeddedKey,
+	visiting map[reflect.Type]bool) []embeddedKey {
+
+	if visiting[t] {
+		return keys // Embeds itself
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	var golific = reflect.PointerTo(t).Implements(jsonDecodableType)
+
+	for i := 0; i < t.NumField(); i++ {
+		var f = t.Field(i)
+		var tag, hasTag = f.Tag.Lookup("json")
+
+		if tag == "-" {
+			continue
+		}
+
+		var name, _, _ = strings.Cut(tag, ",")
+
+		if f.Anonymous && (name == "" || golific) {
+			var ft = f.Type
+			if ft.Kind() == reflect.Ptr {
+				if !f.IsExported() {
+					continue // Can't be allocated
+				}
+				ft = ft.Elem()
+			}
+
+			if ft.Kind() == reflect.Struct {
+				keys = collectKeys(ft, depth+1, keys, visiting)
+				continue
+			}
+		}
+
+		if !f.IsExported() && !(golific && hasTag) {
+			continue
+		}
+
+		if name == "" {
+			keys = append(keys, embeddedKey{name: f.Name, depth: depth})
+		} else {
+			keys = append(keys, embeddedKey{name: name, depth: depth, tagged: true})
+		}
+	}
+
+	return keys
+}
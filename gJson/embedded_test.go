@@ -0,0 +1,89 @@
+package gJson
+
+import "testing"
+
+type deepFields struct {
+	A int
+	C int
+}
+
+type innerFields struct {
+	A int
+	B int `json:"b"`
+	E int
+	deepFields
+}
+
+type otherFields struct {
+	B int
+	D int
+	E int
+	F int `json:"-"`
+}
+
+func TestSplitEmbedded(t *testing.T) {
+	var inner innerFields
+	var other *otherFields
+	var catchAll map[string]int
+
+	for _, test := range []struct {
+		input      string
+		outer      []string
+		ignoreCase bool
+		expect     [3]string
+	}{
+		{ // Shallower fields win, and names shared at the same depth are dropped
+			`{"A":1,"b":2,"C":3,"D":4,"E":5}`, nil, false,
+			[3]string{`{"A":1,"b":2,"C":3}`, `{"D":4}`, ``},
+		},
+		{ // Outer names shadow embedded fields, even when their keys differ by case
+			`{"A":1,"d":4}`, []string{"D"}, false,
+			[3]string{`{"A":1}`, ``, ``},
+		},
+		{ // Keys matching outer fields are left to them
+			`{"a":1,"d":4}`, []string{"A"}, true,
+			[3]string{``, `{"d":4}`, ``},
+		},
+		{ // Exact matches are preferred
+			`{"B":2,"b":3,"c":4}`, nil, false,
+			[3]string{`{"b":3,"c":4}`, `{"B":2}`, ``},
+		},
+		{ // Other keys go to fields of unknown types
+			`{"F":6,"X":7,"A":1}`, nil, false,
+			[3]string{`{"A":1}`, ``, `{"F":6,"X":7}`},
+		},
+		{`{}`, nil, false, [3]string{``, ``, ``}},
+	} {
+		parts, err := SplitEmbedded([]byte(test.input), test.outer, test.ignoreCase,
+			&inner, &other, &catchAll)
+		if err != nil {
+			t.Errorf("%s: %s", test.input, err)
+			continue
+		}
+
+		for i, part := range parts {
+			if string(part) != test.expect[i] {
+				t.Errorf("%s: field %d: expected %s; found %s",
+					test.input, i, test.expect[i], part)
+			}
+		}
+	}
+
+	if _, err := SplitEmbedded([]byte(`[1]`), nil, false, &inner); err == nil {
+		t.Error("expected an error for an array")
+	}
+}
+
+type selfEmbedding struct {
+	A int
+	*selfEmbedding
+}
+
+func TestSplitEmbeddedCycle(t *testing.T) {
+	var s selfEmbedding
+
+	parts, err := SplitEmbedded([]byte(`{"A":1}`), nil, false, &s)
+	if err != nil || string(parts[0]) != `{"A":1}` {
+		t.Errorf("found %s, %v", parts[0], err)
+	}
+}
@@ -10,6 +10,12 @@ type JSONEncodable interface {
 	JSONEncode(*Encoder) bool
 }
 
+// JSONDecodable is implemented by types that can decode themselves from the
+// next value of a Decoder.
+type JSONDecodable interface {
+	JSONDecode(*Decoder) error
+}
+
 type Zeroable interface {
 	IsZero() bool
 }
@@ -27,13 +33,9 @@ func (e *Encoder) EmbedEncodedStruct(je JSONEncodable, isFirst bool) bool {
 	// TODO: Create a pool for this allocation
 	var tempE Encoder
 
-	fmt.Println("Encoding embedded")
-
 	if je.JSONEncode(&tempE) {
-		fmt.Println("Embedded had data")
 		return e.embedResult(tempE.Bytes(), isFirst)
 	}
-	fmt.Println("Embedded had no data")
 	return false
 }
 
@@ -54,24 +56,59 @@ func (e *Encoder) EmbedMarshaledStruct(m interface{}, isFirst bool) bool {
 	}
 }
 
+/*
+EmbedObject is like EmbedEncodedStruct and EmbedMarshaledStruct, except that
+it returns an error instead of panicking if `v` isn't encoded as a JSON object
+or `null`. Nothing is written if there's an error.
+Returns `true` if anything was actually written.
+*/
+func (e *Encoder) EmbedObject(v interface{}, isFirst bool) (bool, error) {
+	if v == nil {
+		return false, nil
+	}
+
+	if je, ok := v.(JSONEncodable); ok {
+		var tempE Encoder
+
+		if !je.JSONEncode(&tempE) {
+			return false, nil
+		}
+		return e.embedObject(tempE.Bytes(), isFirst)
+	}
+
+	r, err := json.Marshal(v)
+	if err != nil {
+		return false, err
+	}
+	return e.embedObject(r, isFirst)
+}
+
 func (e *Encoder) embedResult(b []byte, isFirst bool) bool {
+	wrote, err := e.embedObject(b, isFirst)
+	if err != nil {
+		panic(err.Error())
+	}
+	return wrote
+}
+
+func (e *Encoder) embedObject(b []byte, isFirst bool) (bool, error) {
 	res := bytes.TrimSpace(b)
 
 	if len(res) >= 2 && res[0] == '{' && res[len(res)-1] == '}' {
 		if toEmbed := bytes.TrimSpace(res[1 : len(res)-1]); len(toEmbed) > 0 {
-			if isFirst {
+			if !isFirst {
 				e.writeByte(',')
 			}
 			e.write(toEmbed)
-			return true
+			return true, nil
 		}
-		return false
+		return false, nil
 
 	} else if string(res) == "null" {
 		// Do nothing
-		return false
+		return false, nil
 
 	} else {
-		panic(fmt.Sprintf("Expected a struct; found: %s", res))
+		return false, fmt.Errorf("Expected a JSON object; found: %s", res)
 	}
 }
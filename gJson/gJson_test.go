@@ -0,0 +1,54 @@
+package gJson
+
+import "testing"
+
+type embeddable struct {
+	A int `json:"a,omitempty"`
+}
+
+func TestEmbedCommas(t *testing.T) {
+	for _, test := range []struct {
+		values []interface{}
+		expect string
+	}{
+		{[]interface{}{embeddable{1}}, `{"a":1}`},
+		{[]interface{}{embeddable{1}, embeddable{2}}, `{"a":1,"a":2}`},
+		{[]interface{}{embeddable{}, embeddable{2}}, `{"a":2}`},
+		{[]interface{}{embeddable{1}, nil, embeddable{}, embeddable{3}}, `{"a":1,"a":3}`},
+		{[]interface{}{embeddable{}, embeddable{}}, `{}`},
+	} {
+		var e Encoder
+		var first = true
+
+		e.WriteRawByte('{')
+		for _, v := range test.values {
+			first = !e.EmbedMarshaledStruct(v, first) && first
+		}
+		e.WriteRawByte('}')
+
+		if e.String() != test.expect {
+			t.Errorf("expected %s; found %s", test.expect, e.String())
+		}
+	}
+}
+
+func TestEmbedObject(t *testing.T) {
+	var e Encoder
+
+	e.WriteRawString(`{"k":0`)
+
+	if wrote, err := e.EmbedObject(embeddable{1}, false); !wrote || err != nil {
+		t.Errorf("EmbedObject returned %v, %v", wrote, err)
+	}
+	if wrote, err := e.EmbedObject(5, false); wrote || err == nil {
+		t.Errorf("EmbedObject returned %v, %v; expected an error", wrote, err)
+	}
+	if wrote, err := e.EmbedObject(nil, false); wrote || err != nil {
+		t.Errorf("EmbedObject returned %v, %v", wrote, err)
+	}
+	e.WriteRawByte('}')
+
+	if e.String() != `{"k":0,"a":1}` {
+		t.Errorf("found %s", e.String())
+	}
+}
@@ -140,7 +140,11 @@ func (e *Encoder) EncodeArray(s interface{}, canElide bool) bool {
 	v := reflect.ValueOf(s)
 
 	switch v.Kind() {
-	case reflect.Slice, reflect.Array:
+	case reflect.Slice:
+		if v.IsNil() { // Like encoding/json, a nil slice is `null`
+			return e.EncodeNull(canElide)
+		}
+	case reflect.Array:
 	default: // Can't be encoded as an Array
 		return false
 	}
@@ -159,9 +163,13 @@ func (e *Encoder) EncodeArray(s interface{}, canElide bool) bool {
 		}
 
 		item := v.Index(i)
-		if item.IsNil() {
-			e.EncodeNull(false)
-			continue
+
+		switch item.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+			if item.IsNil() {
+				e.EncodeNull(false)
+				continue
+			}
 		}
 
 		itf := item.Interface()
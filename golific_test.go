@@ -0,0 +1,427 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Runs golific on the source, which is written to a new package inside the
+// module so that the output can import the runtime packages, and returns the
+// directory of the package. Its name starts with `_` so that `./...` never
+// matches it.
+func generate(t *testing.T, src string) string {
+	t.Helper()
+
+	if testing.Short() {
+		t.Skip("builds generated code")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("the go command is not available")
+	}
+
+	dir, err := os.MkdirTemp(".", "_gen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	var path = filepath.Join(dir, "src.go")
+	if err := os.WriteFile(path, []byte(src), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	var data = FileData{Imports: make(map[string]bool, 3)}
+
+	if err := data.DoFile(path); err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range data.Diagnostics {
+		t.Error(d)
+	}
+	if t.Failed() {
+		t.FailNow()
+	}
+	return dir
+}
+
+// Runs the go command with the arguments in the directory, and returns its
+// output. The test fails if the command does.
+func goCmd(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	out, err := exec.Command("go", append(args, "./"+filepath.ToSlash(dir))...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("go %s: %s\n%s", strings.Join(args, " "), err, out)
+	}
+	return string(out)
+}
+
+func TestStructWithoutKeyedFields(t *testing.T) {
+	for _, options := range []string{
+		"", "drop_unmarshal", "case_sensitive", "disallow_unknown_fields",
+	} {
+		t.Run(options, func(t *testing.T) {
+			var dir = generate(t, `package gen
+
+/*
+@struct `+options+`
+*/
+type __Thing struct {
+	Skipped int `+"`json:\"-\"`"+`
+	private int
+}
+`)
+			goCmd(t, dir, "build")
+		})
+	}
+}
+
+func TestEnumUnsignedValues(t *testing.T) {
+	var dir = generate(t, `package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+/*
+@enum json:"value" sql:"value" value_method:"Num"
+*/
+type __Big struct {
+	Small int `+"`gValue:\"1\"`"+`
+	Huge  int `+"`gValue:\"18446744073709551615\"`"+`
+}
+
+/*
+@struct
+*/
+type Holder struct {
+	B BigEnum
+}
+
+func main() {
+	var h = Holder{B: Big.Huge}
+	j, err := json.Marshal(&h)
+	fmt.Println(string(j), err)
+
+	v, err := Big.Huge.Value()
+	fmt.Printf("%T %v %v\n", v, v, err)
+
+	var b BigEnum
+	fmt.Println(b.Scan(v), b == Big.Huge)
+
+	v, err = Big.Small.Value()
+	fmt.Printf("%T %v %v\n", v, v, err)
+}
+`)
+	var out = goCmd(t, dir, "run")
+	var expect = `{"B":18446744073709551615} <nil>
+string 18446744073709551615 <nil>
+<nil> true
+int64 1 <nil>
+`
+	if out != expect {
+		t.Errorf("expected:\n%s\nfound:\n%s", expect, out)
+	}
+}
+
+func TestEnumImports(t *testing.T) {
+	for _, options := range []string{
+		"drop_json drop_text drop_xml",
+		"drop_json drop_text drop_xml bitflags",
+		"drop_json drop_text drop_xml strict",
+		"drop_json drop_text drop_xml bitflags strict",
+		"drop_json drop_text drop_xml open",
+		`drop_json drop_text drop_xml sql:"string" value_method:"Num"`,
+		`drop_text drop_xml json:"string" strict`,
+		`drop_json drop_xml text:"value"`,
+	} {
+		t.Run(options, func(t *testing.T) {
+			var dir = generate(t, `package gen
+
+/*
+@enum `+options+`
+*/
+type __Animal struct {
+	Dog int
+	Cat int
+}
+`)
+			goCmd(t, dir, "build")
+		})
+	}
+}
+
+func TestUnionValueStructVariants(t *testing.T) {
+	var dir = generate(t, `package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// @struct
+type Circle struct {
+	radius int `+"`json:\"radius\"`"+`
+}
+
+/*
+@union
+*/
+type __Internal struct {
+	Circle Circle
+	Ptr    *Circle
+}
+
+/*
+@union json_layout:"adjacent"
+*/
+type __Adjacent struct {
+	Circle Circle
+	Num    int
+}
+
+/*
+@union json_layout:"external"
+*/
+type __External struct {
+	Circle Circle
+	Num    int
+}
+
+func main() {
+	for _, v := range []interface{}{
+		NewInternalCircle(Circle{radius: 1}),
+		NewInternalPtr(&Circle{radius: 2}),
+		NewAdjacentCircle(Circle{radius: 3}),
+		NewAdjacentNum(4),
+		NewExternalCircle(Circle{radius: 5}),
+		NewExternalNum(6),
+	} {
+		j, err := json.Marshal(v)
+		fmt.Println(string(j), err)
+	}
+}
+`)
+	var out = goCmd(t, dir, "run")
+	var expect = `{"type":"Circle","radius":1} <nil>
+{"type":"Ptr","radius":2} <nil>
+{"type":"Circle","value":{"radius":3}} <nil>
+{"type":"Num","value":4} <nil>
+{"Circle":{"radius":5}} <nil>
+{"Num":6} <nil>
+`
+	if out != expect {
+		t.Errorf("expected:\n%s\nfound:\n%s", expect, out)
+	}
+}
+
+func TestStructEmbedding(t *testing.T) {
+	var dir = generate(t, `package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type Plain struct{ A int }
+
+// @struct
+type Coded struct {
+	B int
+}
+
+// @struct
+type Outer struct {
+	Plain
+	*Coded
+	C int
+}
+
+func main() {
+	for _, v := range []Outer{
+		{Plain{1}, &Coded{2}, 3},
+		{Plain{1}, nil, 3},
+	} {
+		j, err := json.Marshal(&v)
+		fmt.Println(string(j), err)
+	}
+}
+`)
+	var out = goCmd(t, dir, "run")
+	var expect = `{"A":1,"B":2,"C":3} <nil>
+{"A":1,"C":3} <nil>
+`
+	if out != expect {
+		t.Errorf("expected:\n%s\nfound:\n%s", expect, out)
+	}
+}
+
+func TestOpenEnumUnmarshal(t *testing.T) {
+	var dir = generate(t, `package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+/*
+@enum json:"string" open
+*/
+type __Str struct {
+	Dog int `+"`gString:\"doggie\"`"+`
+	Cat int `+"`gString:\"kitty\"`"+`
+}
+
+/*
+@enum json:"value" open
+*/
+type __Num struct {
+	Dog int `+"`gString:\"doggie\"`"+`
+	Cat int `+"`gString:\"kitty\"`"+`
+}
+
+// @struct
+type Holder struct {
+	S StrEnum
+	N NumEnum
+}
+
+func main() {
+	for _, in := range []string{"\"kitty\"", "2", "\"lion\"", "7"} {
+		var s StrEnum
+		var n NumEnum
+		var errS = json.Unmarshal([]byte(in), &s)
+		var errN = json.Unmarshal([]byte(in), &n)
+		js, _ := json.Marshal(s)
+		jn, _ := json.Marshal(n)
+		fmt.Println(in, string(js), errS, string(jn), errN)
+
+		var h Holder
+		var err = h.UnmarshalJSON([]byte("{\"S\":" + in + ",\"N\":" + in + "}"))
+		jh, _ := json.Marshal(&h)
+		fmt.Println(string(jh), err)
+	}
+}
+`)
+	var out = goCmd(t, dir, "run")
+	var expect = `"kitty" "kitty" <nil> 2 <nil>
+{"S":"kitty","N":2} <nil>
+2 "kitty" <nil> 2 <nil>
+{"S":"kitty","N":2} <nil>
+"lion" "lion" <nil> "lion" <nil>
+{"S":"lion","N":"lion"} <nil>
+7 7 <nil> 7 <nil>
+{"S":7,"N":7} <nil>
+`
+	if out != expect {
+		t.Errorf("expected:\n%s\nfound:\n%s", expect, out)
+	}
+}
+
+func TestBitflagSeparator(t *testing.T) {
+	var dir = generate(t, `package main
+
+import "fmt"
+
+/*
+@enum bitflags
+*/
+type __Joined struct {
+	A  int `+"`gString:\"a\"`"+`
+	AB int `+"`gString:\"ab\"`"+`
+	C  int `+"`gString:\"c\" gAliases:\"see\"`"+`
+}
+
+/*
+@enum bitflags bitflag_separator:","
+*/
+type __Split struct {
+	A  int `+"`gString:\"a\"`"+`
+	AB int `+"`gString:\"ab\"`"+`
+	C  int `+"`gString:\"c\"`"+`
+}
+
+func main() {
+	var j = Joined.A.AddAll(Joined.AB, Joined.C)
+	text, _ := j.MarshalText()
+	fmt.Println(string(text))
+
+	for _, s := range []string{"aabc", "ABa", "seea", "", "abx"} {
+		v, err := ParseJoined(s)
+		fmt.Printf("%q %q %v\n", s, v, err)
+	}
+
+	var s = Split.A.AddAll(Split.AB, Split.C)
+	text, _ = s.MarshalText()
+	fmt.Println(string(text))
+
+	var back SplitEnum
+	fmt.Println(back.UnmarshalText(text), back == s)
+}
+`)
+	var out = goCmd(t, dir, "run")
+	var expect = `aabc
+"aabc" "aabc" <nil>
+"ABa" "aab" <nil>
+"seea" "ac" <nil>
+"" "" <nil>
+"abx" "" "x" is not a variant of Joined
+a,ab,c
+<nil> true
+`
+	if out != expect {
+		t.Errorf("expected:\n%s\nfound:\n%s", expect, out)
+	}
+}
+
+func TestStructEmbeddedDecoding(t *testing.T) {
+	var dir = generate(t, `package main
+
+import "fmt"
+
+type Plain struct {
+	A int
+	Name string
+}
+
+// @struct
+type Coded struct {
+	B    int
+	Name string
+}
+
+// @struct
+type Outer struct {
+	Plain
+	*Coded
+	Name string
+}
+
+func main() {
+	for _, in := range []string{
+		`+"`"+`{"A":1,"Name":"n"}`+"`"+`,
+		`+"`"+`{"b":2,"name":"m"}`+"`"+`,
+		`+"`"+`{}`+"`"+`,
+	} {
+		var o Outer
+		fmt.Println(o.UnmarshalJSON([]byte(in)), o.Plain, o.Coded != nil, o.Name)
+		if o.Coded != nil {
+			fmt.Println(*o.Coded)
+		}
+	}
+}
+`)
+	var out = goCmd(t, dir, "run")
+	var expect = `<nil> {1 } false n
+<nil> {0 } true m
+{2 }
+<nil> {0 } false 
+`
+	if out != expect {
+		t.Errorf("expected:\n%s\nfound:\n%s", expect, out)
+	}
+}
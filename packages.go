@@ -0,0 +1,282 @@
+package main
+
+import (
+	"fmt"
+	"go/build"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const generatedPrefix = "golific____"
+
+// The first line of every generated file, in the form recognized by Go tools.
+const generatedHeader = "// Code generated by golific; DO NOT EDIT."
+
+// Precedes the name of the source file on the second line of generated files.
+const sourcePrefix = "// Source: "
+
+/*
+Expands the command line arguments into the list of files to process. An
+argument may be a .go file, a directory holding a package, or a directory
+followed by `/...` to include every package below it. Packages are loaded with
+the given build tags, and their test files are included.
+*/
+func expandPaths(paths []string, tags []string) (files []string, err error) {
+	var ctx = build.Default
+	ctx.BuildTags = append(ctx.BuildTags, tags...)
+
+	var seen = make(map[string]bool)
+
+	var add = func(file string) {
+		if !seen[file] {
+			seen[file] = true
+			files = append(files, file)
+		}
+	}
+
+	for _, path := range paths {
+		var dirs []string
+
+		switch {
+		case path == "..." || strings.HasSuffix(path, "/..."):
+			var root = strings.TrimSuffix(strings.TrimSuffix(path, "..."), "/")
+			if root == "" {
+				root = "."
+			}
+			if dirs, err = walkDirs(root); err != nil {
+				return nil, err
+			}
+
+		case strings.HasSuffix(path, ".go"):
+			add(path)
+			continue
+
+		default:
+			dirs = []string{path}
+		}
+
+		for _, dir := range dirs {
+			pkgFiles, err := packageFiles(&ctx, dir)
+			if err != nil {
+				return nil, err
+			}
+			for _, file := range pkgFiles {
+				add(file)
+			}
+		}
+	}
+
+	return files, nil
+}
+
+// Returns the files of the package in `dir` that match the build context,
+// including test files, but not files generated by Golific.
+func packageFiles(ctx *build.Context, dir string) ([]string, error) {
+	pkg, err := ctx.ImportDir(dir, 0)
+	if err != nil {
+		if _, ok := err.(*build.NoGoError); ok {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var paths []string
+	for _, list := range [...][]string{
+		pkg.GoFiles, pkg.CgoFiles, pkg.TestGoFiles, pkg.XTestGoFiles,
+	} {
+		for _, name := range list {
+			var path = filepath.Join(dir, name)
+
+			if _, ok := generatedSource(path); !ok &&
+				!strings.HasPrefix(name, generatedPrefix) {
+				paths = append(paths, path)
+			}
+		}
+	}
+
+	sort.Strings(paths)
+
+	return paths, nil
+}
+
+// Returns `root` and every directory below it, skipping the ones the go tool
+// ignores.
+func walkDirs(root string) (dirs []string, err error) {
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if path != root {
+			var name = info.Name()
+			if name == "testdata" || name == "vendor" ||
+				strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+		}
+		dirs = append(dirs, path)
+		return nil
+	})
+	return dirs, err
+}
+
+/*
+Returns the import path of the package in `dir`. It comes from the `module`
+line of the nearest go.mod, or else from the location of `dir` in GOPATH. If
+neither is found, the package name is used.
+*/
+func packagePath(dir, pkgName string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return pkgName
+	}
+
+	for modDir := abs; ; {
+		if data, err := os.ReadFile(filepath.Join(modDir, "go.mod")); err == nil {
+			if mod := modulePath(data); mod != "" {
+				rel, _ := filepath.Rel(modDir, abs)
+				return path.Join(mod, filepath.ToSlash(rel))
+			}
+		}
+
+		parent := filepath.Dir(modDir)
+		if parent == modDir {
+			break
+		}
+		modDir = parent
+	}
+
+	if pkg, err := build.Default.ImportDir(abs, build.FindOnly); err == nil &&
+		pkg.ImportPath != "." && !strings.HasPrefix(pkg.ImportPath, "_") {
+		return pkg.ImportPath
+	}
+
+	return pkgName
+}
+
+// Returns the path from the `module` line of a go.mod file.
+func modulePath(data []byte) string {
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); strings.HasPrefix(line, "module") {
+			line = strings.TrimSpace(strings.TrimPrefix(line, "module"))
+			if unquoted, err := strconv.Unquote(line); err == nil {
+				return unquoted
+			}
+			return line
+		}
+	}
+	return ""
+}
+
+/*
+Returns the path of the file generated for the source file `filename` in
+`dir`. The -o flag gives the path directly. Otherwise the -suffix flag is
+added to the name, before any `_test` suffix, or else the name is given the
+"golific____" prefix.
+*/
+func outputName(dir, filename string) string {
+	if *outFlag != "" {
+		return *outFlag
+	}
+
+	if *suffixFlag == "" {
+		return filepath.Join(dir, generatedPrefix+filename)
+	}
+
+	var base = strings.TrimSuffix(filename, ".go")
+	var test = ""
+
+	if strings.HasSuffix(base, "_test") {
+		base, test = strings.TrimSuffix(base, "_test"), "_test"
+	}
+
+	return filepath.Join(dir, base+*suffixFlag+test+".go")
+}
+
+/*
+Returns the path of the source file that the file at `path` was generated
+from, and 'false' if the file wasn't generated by Golific. Files generated
+before the header was added are recognized by their prefix.
+*/
+func generatedSource(path string) (string, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+
+	var dir, filename = filepath.Split(path)
+	var lines = strings.SplitN(string(data), "\n", 3)
+
+	if lines[0] == generatedHeader {
+		if len(lines) > 1 && strings.HasPrefix(lines[1], sourcePrefix) {
+			var source = strings.TrimPrefix(lines[1], sourcePrefix)
+			return filepath.Join(dir, filepath.FromSlash(source)), true
+		}
+		return "", false
+	}
+
+	if strings.HasPrefix(filename, generatedPrefix) &&
+		strings.Contains(string(data), "This file was generated by Golific.") {
+		return filepath.Join(dir, strings.TrimPrefix(filename, generatedPrefix)), true
+	}
+
+	return "", false
+}
+
+// Removes the file at `path` if it exists and was generated by Golific.
+func removeGenerated(path string) error {
+	if _, ok := generatedSource(path); !ok {
+		return nil
+	}
+
+	fmt.Printf("Removing file: %q\n", path)
+	return os.Remove(path)
+}
+
+/*
+Removes the files generated by Golific in the directories of `files` whose
+source files no longer exist. `outputs` holds the output path of each source
+file that was processed without errors, so that files generated from it under
+another name, like after switching between the prefix and -suffix, are removed
+too.
+*/
+func removeOrphans(files []string, outputs map[string]string) error {
+	var dirs = make(map[string]bool)
+
+	for _, file := range files {
+		dirs[filepath.Dir(file)] = true
+	}
+
+	for dir := range dirs {
+		paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+		if err != nil {
+			return err
+		}
+
+		for _, path := range paths {
+			source, ok := generatedSource(path)
+			if !ok {
+				continue
+			}
+
+			if out, ok := outputs[filepath.Clean(source)]; ok {
+				if filepath.Clean(out) == filepath.Clean(path) {
+					continue
+				}
+			} else if _, err := os.Stat(source); !os.IsNotExist(err) {
+				continue
+			}
+
+			if err := removeGenerated(path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
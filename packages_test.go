@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRemoveOrphans(t *testing.T) {
+	var dir = t.TempDir()
+
+	var write = func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var generated = func(source string) string {
+		return generatedHeader + "\n" + sourcePrefix + source + "\n\npackage p\n"
+	}
+
+	write("a.go", "package p\n")
+	write("b.go", "package p\n")
+	write(generatedPrefix+"a.go", generated("a.go")) // Named by the old scheme
+	write("a_gen.go", generated("a.go"))
+	write(generatedPrefix+"b.go", generated("b.go")) // Its source wasn't processed
+	write("c_gen.go", generated("c.go"))             // Its source is gone
+	write(generatedPrefix+"d.go", "package p\n")     // Not generated
+
+	var files = []string{filepath.Join(dir, "a.go")}
+	var outputs = map[string]string{files[0]: filepath.Join(dir, "a_gen.go")}
+
+	if err := removeOrphans(files, outputs); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, expect := range map[string]bool{
+		generatedPrefix + "a.go": false,
+		"a_gen.go":               true,
+		generatedPrefix + "b.go": true,
+		"c_gen.go":               false,
+		generatedPrefix + "d.go": true,
+	} {
+		if _, err := os.Stat(filepath.Join(dir, name)); (err == nil) != expect {
+			t.Errorf("%s: expected to exist: %v; found: %v", name, expect, err == nil)
+		}
+	}
+}
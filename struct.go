@@ -1,9 +1,9 @@
 package main
 
 import (
-	"fmt"
 	"go/ast"
 	"go/token"
+	"strconv"
 	"strings"
 )
 
@@ -18,12 +18,12 @@ type StructRepr struct {
 
 type StructFieldRepr struct {
 	BaseFieldRepr
-	DefaultExpr string // Default expression
-	JsonName    string // Name used for json [un]marshaling
-	JsonNameCI  string // Case insensitive version of JsonName
-	astField    *ast.Field
+	JsonName   string // Name used for json [un]marshaling
+	JsonNameCI string // Case insensitive version of JsonName
+	astField   *ast.Field
 }
 
+// The built-in defaults, before any config file or @struct-defaults is applied
 var structDefaults StructDefaults
 
 func (self *StructDefaults) gatherFlags(tagText string) error {
@@ -32,14 +32,77 @@ func (self *StructDefaults) gatherFlags(tagText string) error {
 		case "drop_json": // Do not generate JSON marshaling methods
 			return self.doBooleanFlag(flag, dropJson)
 
+		case "drop_unmarshal": // Do not generate the JSON unmarshaling method
+			return self.doBooleanFlag(flag, dropUnmarshal)
+
+		case "case_sensitive": // Keys must match the JSON name exactly
+			return self.doBooleanFlag(flag, caseSensitive)
+
+		case "disallow_unknown_fields": // Keys matching no field are an error
+			return self.doBooleanFlag(flag, disallowUnknownFields)
+
 		default:
 			return UnknownFlag
 		}
 	})
 }
 
-func (self *StructRepr) HasPrivateJSON() bool {
-	return self.flags&hasPrivateJSON == hasPrivateJSON
+func (self *StructRepr) DoJson() bool { return self.flags&dropJson == 0 }
+func (self *StructRepr) DoUnmarshal() bool {
+	return self.flags&(dropJson|dropUnmarshal) == 0
+}
+func (self *StructRepr) HasEmbeddedFields() bool {
+	return self.flags&hasEmbeddedFields == hasEmbeddedFields
+}
+func (self *StructRepr) IsCaseSensitive() bool {
+	return self.flags&caseSensitive == caseSensitive
+}
+func (self *StructRepr) DisallowUnknownFields() bool {
+	return self.flags&disallowUnknownFields == disallowUnknownFields
+}
+
+// Returns true if any field is decoded by key, as opposed to being embedded.
+func (self *StructRepr) HasDecodedFields() bool {
+	for _, f := range self.Fields {
+		if f.DoDecode() {
+			return true
+		}
+	}
+	return false
+}
+
+func (self *StructRepr) EmbeddedFields() (res []*StructFieldRepr) {
+	for _, f := range self.Fields {
+		if f.IsEmbedded() {
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+// DecodedJsonNames returns the JSON names of the fields decoded by key, as a Go
+// slice literal.
+func (self *StructRepr) DecodedJsonNames() string {
+	var names []string
+	for _, f := range self.Fields {
+		if f.DoDecode() {
+			names = append(names, strconv.Quote(f.JsonName))
+		}
+	}
+	if len(names) == 0 {
+		return "nil"
+	}
+	return "[]string{" + strings.Join(names, ", ") + "}"
+}
+
+// Returns true if any field is encoded by key, as opposed to being embedded.
+func (self *StructRepr) HasKeyedFields() bool {
+	for _, f := range self.Fields {
+		if f.DoEncode() {
+			return true
+		}
+	}
+	return false
 }
 
 func (self *StructFieldRepr) HasJSONOmitEmpty() bool {
@@ -49,27 +112,56 @@ func (self *StructFieldRepr) IsEmbedded() bool {
 	return self.flags&embedded == embedded
 }
 
-func (sf *StructFieldRepr) IsPrivateField() bool {
-	return sf.flags&privateJSON == privateJSON
-}
-func (sf *StructFieldRepr) IsPrivateJSON() bool {
-	return sf.flags&(privateJSON|hasJsonTag) == (privateJSON | hasJsonTag)
-}
 func (sf *StructFieldRepr) HasJsonTag() bool {
 	return sf.flags&hasJsonTag == hasJsonTag
 }
+func (sf *StructFieldRepr) IsJsonSkipped() bool {
+	return sf.flags&jsonSkip == jsonSkip
+}
+
+// Returns true if the field is encoded and decoded with its own key. Unexported
+// fields need a json tag, unlike with encoding/json, which ignores them.
+func (sf *StructFieldRepr) DoDecode() bool {
+	return !sf.IsEmbedded() && !sf.IsJsonSkipped() &&
+		(isExportedIdent(sf.Name) || sf.HasJsonTag())
+}
+func (sf *StructFieldRepr) DoEncode() bool {
+	return sf.DoDecode()
+}
+
+// Returns the JSON name in the form that UnmarshalJSON compares keys with,
+// which is lowercase unless the struct is case sensitive.
+func (sf *StructFieldRepr) GetJsonKey(s *StructRepr) string {
+	if s.IsCaseSensitive() {
+		return sf.JsonName
+	}
+	return sf.JsonNameCI
+}
 
 // Gets the Name, which may be the Type for embedded fields. If so, it strips
-// away any leading `*`
+// away any leading `*`, package name and type arguments, as Go does to name the
+// field.
 func (self *StructFieldRepr) GetNameMaybeType() string {
 	if self.IsEmbedded() {
-		return strings.TrimLeft(self.Type, "*")
+		var name = strings.TrimLeft(self.Type, "*")
+
+		if idx := strings.IndexByte(name, '['); idx != -1 {
+			name = name[:idx]
+		}
+		if idx := strings.LastIndexByte(name, '.'); idx != -1 {
+			name = name[idx+1:]
+		}
+		return name
 	}
 	return self.Name
 }
 
 func (self *FileData) doStructDefaults(tagText string) error {
-	return structDefaults.gatherFlags(tagText)
+	if tagText, ok := cutReset(tagText); ok {
+		self.structDefaults = self.config.structDefaults
+		return self.structDefaults.gatherFlags(tagText)
+	}
+	return self.structDefaults.gatherFlags(tagText)
 }
 
 func (self *FileData) newStruct(fset *token.FileSet, tagText string,
@@ -78,7 +170,7 @@ func (self *FileData) newStruct(fset *token.FileSet, tagText string,
 	var err error
 
 	strct_repr := StructRepr{
-		StructDefaults: structDefaults, // copy of current defaults
+		StructDefaults: self.structDefaults, // copy of current defaults
 	}
 	strct_repr.fset = fset
 
@@ -86,16 +178,20 @@ func (self *FileData) newStruct(fset *token.FileSet, tagText string,
 		return err
 	}
 
-	/*
-		if err = strct_repr.gatherFlags(tagText); err != nil {
-			return err
-		}
-	*/
+	if err = strct_repr.gatherFlags(tagText); err != nil {
+		return strct_repr.positioned(err)
+	}
 
 	if err = strct_repr.doFields(strct.Fields); err != nil {
 		return err
 	}
 
+	if strct_repr.DoUnmarshal() && strct_repr.DisallowUnknownFields() &&
+		strct_repr.flags&hasEmbeddedFields == hasEmbeddedFields {
+		return strct_repr.errorf("The 'disallow_unknown_fields' option can not be " +
+			"used with embedded fields, whose keys are unknown")
+	}
+
 	self.Structs = append(self.Structs, &strct_repr)
 
 	return nil
@@ -103,7 +199,7 @@ func (self *FileData) newStruct(fset *token.FileSet, tagText string,
 
 func (self *StructRepr) doFields(fields *ast.FieldList) (err error) {
 	if len(fields.List) == 0 {
-		return fmt.Errorf("@structs must have at least one field defined")
+		return self.errorf("@structs must have at least one field defined")
 	}
 
 	for _, field := range fields.List {
@@ -111,7 +207,7 @@ func (self *StructRepr) doFields(fields *ast.FieldList) (err error) {
 		f.fset = self.fset
 
 		if err := f.gatherCodeCommentsAndName(field, true); err != nil {
-			return err
+			return f.positioned(err)
 		}
 
 		f.JsonName = f.Name
@@ -121,17 +217,24 @@ func (self *StructRepr) doFields(fields *ast.FieldList) (err error) {
 
 		} else {
 			if err = f.gatherFlags(getFlags(field.Tag)); err != nil {
-				return err
-			}
-
-			if !isExportedIdent(f.Name) && f.flags&hasJsonTag == hasJsonTag {
-				f.flags |= privateJSON
-				self.flags |= hasPrivateJSON
+				return f.positioned(err)
 			}
 		}
 
 		f.JsonNameCI = strings.ToLower(f.JsonName)
 
+		if f.DoDecode() {
+			var key = f.GetJsonKey(self)
+
+			for _, other := range self.Fields {
+				if other.DoDecode() && other.GetJsonKey(self) == key {
+					return f.errorf("The JSON name of %q matches that of %q. Use "+
+						"the 'case_sensitive' option if they differ by case.",
+						f.Name, other.Name)
+				}
+			}
+		}
+
 		self.Fields = append(self.Fields, &f)
 	}
 
@@ -144,7 +247,10 @@ func (self *StructFieldRepr) gatherFlags(tagText string) error {
 		case "json": // Just to find out if it has `omitempty`
 			self.flags |= hasJsonTag
 
-			if len(flag.Value) > 0 {
+			if flag.Value == "-" {
+				self.flags |= jsonSkip
+
+			} else if len(flag.Value) > 0 {
 				if idx := strings.IndexByte(flag.Value, ','); idx == -1 {
 					self.JsonName = flag.Value
 
@@ -171,33 +277,37 @@ func (self *StructFieldRepr) gatherFlags(tagText string) error {
 }
 
 func (self *FileData) GatherStructImports() {
-	if len(self.Structs) == 0 {
-		return
-	}
-	self.Imports["Golific/gJson"] = true
-	self.Imports["reflect"] = true
+	for _, s := range self.Structs {
+		if !s.DoJson() {
+			continue
+		}
 
-	self.Imports["encoding/json"] = true
+		self.Imports["Golific/gJson"] = true
+
+		if s.HasKeyedFields() {
+			self.Imports["reflect"] = true
+		}
+
+		if !s.DoUnmarshal() {
+			continue
+		}
+
+		if s.HasDecodedFields() || s.DisallowUnknownFields() {
+			self.Imports["fmt"] = true
+		}
+	}
 }
 
 func (self *StructFieldRepr) MaybeStruct() bool {
-	switch n := self.astField.Type.(type) {
+	switch self.astField.Type.(type) {
 	case *ast.ArrayType, *ast.MapType:
 		return false
-
-	case *ast.Ident:
-		switch n.Name {
-		case "bool", "string",
-			"int", "int64", "int32", "int16", "int8", "uint", "uint64", "uint32",
-			"uint16", "uint8", "float64", "float32":
-			return false
-		}
 	}
-	return true
+	return !isBasicType(self.astField.Type)
 }
 
 func (self *StructFieldRepr) CantAvoidEncodingAttempt() string {
-	if self.IsPrivateField() && self.HasJsonTag() == false {
+	if !self.DoEncode() {
 		return "false"
 	}
 
@@ -236,6 +346,7 @@ var struct_tmpl = `
 {{$struct.Name}} struct
 
 ******************************/
+{{- if $struct.DoJson}}
 
 // JSONEncode implements part of Golific's JSONEncodable interface.
 func (self *{{$struct.Name}}) JSONEncode(encoder *gJson.Encoder) bool {
@@ -255,7 +366,7 @@ func (self *{{$struct.Name}}) JSONEncode(encoder *gJson.Encoder) bool {
 		first = !encoder.EmbedMarshaledStruct(self.{{$f.GetNameMaybeType}}, first) && first
 	}
 
-	{{else -}}
+	{{else if $f.DoEncode -}}
 
 	if {{$f.CantAvoidEncodingAttempt}} {
 		var d interface{} = self.{{$f.Name}}
@@ -293,58 +404,79 @@ func (self *{{$struct.Name}}) MarshalJSON() ([]byte, error) {
 	return encoder.Bytes(), nil
 }
 
+
+{{- if $struct.DoUnmarshal}}
+
+// UnmarshalJSON implements the json.Unmarshaler interface using JSONDecode.
 func (self *{{$struct.Name}}) UnmarshalJSON(j []byte) error {
-	if len(j) == 4 && string(j) == "null" {
-		return nil
-	}
+	var d = gJson.NewDecoder(j)
 
-	// First unmarshal using the default unmarshaler. The temp type is so that
-	// this method is not called recursively.
-	type temp *{{$struct.Name}}
-	if err := json.Unmarshal(j, temp(self)); err != nil {
+	if err := self.JSONDecode(d); err != nil {
 		return err
 	}
+	return d.End()
+}
 
-	{{if $struct.HasPrivateJSON}}
+// JSONDecode implements Golific's JSONDecodable interface. It decodes the value
+// of each key of the object into the field with that JSON name, in a single
+// pass. Fields whose keys are absent are left unchanged.
+{{- if not $struct.IsCaseSensitive}}
+// Keys are matched ignoring case.
+{{- end}}
+{{- if $struct.DisallowUnknownFields}}
+// A key that matches no field is an error.
+{{- end}}
+func (self *{{$struct.Name}}) JSONDecode(d *gJson.Decoder) error {
+	if d.ReadNull() {
+		return nil
+	}
 
-	// For every property found, perform a separate UnmarshalJSON operation. This
-	// prevents overwrite of values in 'self' where properties are absent.
-	m := make(map[string]json.RawMessage)
+	{{- if $struct.HasEmbeddedFields}}
 
-	err := json.Unmarshal(j, &m)
+	// The keys of embedded fields are left to the embedded types. A field is
+	// only decoded, or allocated if it's a pointer, when one of its keys is in
+	// the object.
+	raw, err := d.PeekRaw()
 	if err != nil {
 		return err
 	}
 
-	// JSON key comparisons are case-insensitive
-	for k, v := range m {
-		m[strings.ToLower(k)] = v
+	parts, err := gJson.SplitEmbedded(raw, {{$struct.DecodedJsonNames}}, {{not $struct.IsCaseSensitive}},
+		{{- range $f := $struct.EmbeddedFields}} &self.{{$f.GetNameMaybeType}},{{end}})
+	if err != nil {
+		return err
 	}
+	{{- range $i, $f := $struct.EmbeddedFields}}
 
-	var data json.RawMessage
-	var ok bool
-
-	{{- range $f := $struct.Fields -}}
-	{{- if $f.IsPrivateJSON}}
-	if data, ok = m[{{printf "%q" $f.JsonNameCI}}]; ok {
-		var temp struct{ {{$f.OriginalCode}} }
-		data = append(append([]byte("{ \"{{$f.JsonNameCI}}\":"), data...), '}')
-
-		if err = json.Unmarshal(data, &temp); err != nil {
-			return fmt.Errorf(
-				"Field: %s, Error: %s", {{printf "%q" $f.JsonNameCI}}, err.Error(),
-			)
+	if parts[{{$i}}] != nil {
+		if err := gJson.NewDecoder(parts[{{$i}}]).Decode(&self.{{$f.GetNameMaybeType}}); err != nil {
+			return err
 		}
-
-		self.{{$f.Name}} = temp.{{$f.Name}}
 	}
-	{{end -}}
-	{{end -}}
-
-	{{end -}}
-
-  return nil
+	{{- end}}
+	{{- end}}
+
+	return d.ReadObject(func(key []byte) error {
+		{{- range $f := $struct.Fields}}
+		{{- if $f.DoDecode}}
+		if gJson.KeyMatches(key, {{printf "%q" $f.JsonName}}, {{not $struct.IsCaseSensitive}}) {
+			if err := d.Decode(&self.{{$f.Name}}); err != nil {
+				return fmt.Errorf("Field: %s, Error: %s", key, err.Error())
+			}
+			return nil
+		}
+		{{- end}}
+		{{- end}}
+
+		{{- if $struct.DisallowUnknownFields}}
+		return fmt.Errorf("Unknown field %q while unmarshaling {{$struct.Name}}", key)
+		{{- else}}
+		return d.Skip()
+		{{- end}}
+	})
 }
+{{- end}}
+{{- end}}
 
 {{end -}}
 {{end -}}
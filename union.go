@@ -0,0 +1,557 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+)
+
+type UnionDefaults struct {
+	BaseRepr
+	kindSuffix string // "Kind"
+	jsonLayout string // "internal"
+	JsonTag    string // "type"
+	JsonValue  string // "value"
+}
+
+type UnionRepr struct {
+	UnionDefaults
+	Fields []*UnionFieldRepr
+	Kind   *EnumRepr // Enum generated to identify the variant held by the union
+}
+
+type UnionFieldRepr struct {
+	BaseFieldRepr
+	String      string
+	Aliases     []string
+	Description string
+	astType     ast.Expr
+}
+
+// Layouts for the JSON representation of a union
+const (
+	jsonLayoutInternal = "internal" // {"type":"circle","radius":1}
+	jsonLayoutAdjacent = "adjacent" // {"type":"circle","value":{"radius":1}}
+	jsonLayoutExternal = "external" // {"circle":{"radius":1}}
+)
+
+// The built-in defaults, before any config file or @union-defaults is applied
+var unionDefaults UnionDefaults
+
+func init() {
+	unionDefaults.kindSuffix = "Kind"
+	unionDefaults.jsonLayout = jsonLayoutInternal
+	unionDefaults.JsonTag = "type"
+	unionDefaults.JsonValue = "value"
+	unionDefaults.flags = 0
+}
+
+func (self *UnionRepr) DoJson() bool { return self.flags&dropJson == 0 }
+
+func (self *UnionRepr) IsAdjacentJson() bool {
+	return self.jsonLayout == jsonLayoutAdjacent
+}
+func (self *UnionRepr) GetJsonLayout() string { return self.jsonLayout }
+func (self *UnionRepr) IsExternalJson() bool {
+	return self.jsonLayout == jsonLayoutExternal
+}
+
+func (self *UnionRepr) GetKindField() string {
+	return "kind_" + self.getUniqueId()
+}
+func (self *UnionRepr) GetValueField() string {
+	return "value_" + self.getUniqueId()
+}
+
+func (self *UnionRepr) GetKindName() string {
+	if len(self.kindSuffix) == 0 {
+		return self.Name + "Kind"
+	}
+	return self.Name + self.kindSuffix
+}
+
+func (self *FileData) doUnionDefaults(tagText string) error {
+	if tagText, ok := cutReset(tagText); ok {
+		self.unionDefaults = self.config.unionDefaults
+		return self.unionDefaults.gatherFlags(tagText)
+	}
+	return self.unionDefaults.gatherFlags(tagText)
+}
+
+func (ud *UnionDefaults) gatherFlags(tagText string) error {
+	return ud.genericGatherFlags(tagText, func(flag Flag) (err error) {
+		switch flag.Name {
+		case "kind_suffix": // Suffix added to the union name for the kind enum
+			if ud.kindSuffix, err = flag.getNonEmpty(); err != nil {
+				return err
+			}
+
+		case "json_layout": // Set the layout of the JSON representation
+			if ud.jsonLayout, err = flag.getNonEmpty(); err != nil {
+				return err
+			}
+			switch ud.jsonLayout {
+			case jsonLayoutInternal, jsonLayoutAdjacent, jsonLayoutExternal:
+			default:
+				return fmt.Errorf("Unexpected value %q for %q", flag.Value, flag.Name)
+			}
+
+		case "json_tag": // Key holding the discriminator for internal and adjacent
+			if ud.JsonTag, err = flag.getNonEmpty(); err != nil {
+				return err
+			}
+
+		case "json_value": // Key holding the variant for the adjacent layout
+			if ud.JsonValue, err = flag.getNonEmpty(); err != nil {
+				return err
+			}
+
+		case "drop_json": // Do not generate JSON marshaling methods
+			return ud.doBooleanFlag(flag, dropJson)
+
+		case "random_id": // Name the private fields with a new random id every run
+			return ud.doBooleanFlag(flag, randomId)
+
+		default:
+			return UnknownFlag
+		}
+		return nil
+	})
+}
+
+func (self *FileData) newUnion(fset *token.FileSet, tagText string,
+	docs []*ast.Comment, spec *ast.TypeSpec, strct *ast.StructType) error {
+
+	var err error
+
+	union := UnionRepr{
+		UnionDefaults: self.unionDefaults, // copy of current defaults
+	}
+	union.fset = fset
+
+	if err = union.setDocsAndName(docs, spec, true); err != nil {
+		return err
+	}
+
+	if err = union.gatherFlags(tagText); err != nil {
+		return union.positioned(err)
+	}
+
+	union.unique = self.uniqueId(&union.Base, union.Name)
+
+	if err = union.doFields(strct.Fields); err != nil {
+		return err
+	}
+
+	if union.Kind, err = union.newKindEnum(); err != nil {
+		return err
+	}
+
+	union.Kind.unique = self.uniqueId(&union.Base, union.Kind.Name)
+
+	self.Unions = append(self.Unions, &union)
+	self.Enums = append(self.Enums, union.Kind)
+
+	return nil
+}
+
+func (self *UnionRepr) doFields(fields *ast.FieldList) (err error) {
+	for _, field := range fields.List {
+		var f = UnionFieldRepr{astType: field.Type}
+		f.fset = self.fset
+
+		if err = f.gatherCodeCommentsAndName(field, false); err != nil {
+			return f.positioned(err)
+		}
+
+		if f.Name == "Values" {
+			return f.errorf("The variant named %q conflicts with the iterator of "+
+				"the kind enum.", f.Name)
+		}
+
+		for _, method := range [...]string{"Is" + f.Name, "As" + f.Name} {
+			if self.isMethodName(method) {
+				return f.errorf("The variant named %q conflicts with the %s method "+
+					"of the union.", f.Name, method)
+			}
+		}
+
+		// Flags come from the struct field tag
+		if err = f.gatherFlags(getFlags(field.Tag)); err != nil {
+			return f.positioned(err)
+		}
+
+		if self.DoJson() && self.jsonLayout == jsonLayoutInternal &&
+			!maybeJsonObject(field.Type) {
+			return f.errorf("The variant %q can not be encoded as a JSON object, "+
+				"which the %q json_layout requires.", f.Name, self.jsonLayout)
+		}
+
+		// Set values if no string or description value is given
+		if len(f.String) == 0 {
+			f.String = f.Name
+		}
+		if len(f.Description) == 0 {
+			f.Description = f.String
+		}
+
+		self.Fields = append(self.Fields, &f)
+	}
+
+	if len(self.Fields) == 0 {
+		return self.errorf("Unions must have at least one variant defined")
+	}
+
+	return nil
+}
+
+func (self *UnionFieldRepr) gatherFlags(tagText string) error {
+	return self.genericGatherFlags(tagText, func(flag Flag) (err error) {
+		switch flag.Name {
+		case "gString": // The string representation of the variant's kind
+			if self.String, err = flag.getWithColon(); err != nil {
+				return err
+			}
+
+		case "gDescription": // The description of the variant's kind
+			if self.Description, err = flag.getWithColon(); err != nil {
+				return err
+			}
+
+		case "gAliases": // Other strings accepted for the variant's kind
+			if self.Aliases, err = getAliases(flag); err != nil {
+				return err
+			}
+
+		default:
+			return UnknownFlag
+		}
+		return nil
+	})
+}
+
+// Builds the enum used to identify which variant a union holds. Each variant
+// of the enum shares its name, string, aliases and description with a union
+// field.
+func (self *UnionRepr) newKindEnum() (*EnumRepr, error) {
+	var kind = EnumRepr{}
+
+	kind.fset = self.fset
+	kind.pos = self.pos
+	kind.Name = self.GetKindName()
+	kind.iterName = "Values"
+	kind.flags = jsonMarshalIsString | jsonUnmarshalIsString |
+		textMarshalIsString | textUnmarshalIsString |
+		xmlMarshalIsString | xmlUnmarshalIsString | strict
+	kind.docs = []string{fmt.Sprintf(
+		"// %sEnum identifies the variant held by a %s.", kind.Name, self.Name,
+	)}
+
+	for i, uf := range self.Fields {
+		var f = EnumFieldRepr{}
+
+		f.fset = self.fset
+		f.pos = uf.pos
+		f.Name = uf.Name
+		f.docs = uf.docs
+		f.String = uf.String
+		f.Aliases = uf.Aliases
+		f.Description = uf.Description
+		f.Value = int64(i + 1)
+
+		kind.Fields = append(kind.Fields, &f)
+	}
+
+	return &kind, kind.validate()
+}
+
+// Reports whether a variant of the given type could be encoded as a JSON
+// object, which is needed for its fields to be merged with the discriminator.
+func maybeJsonObject(typ ast.Expr) bool {
+	switch typ.(type) {
+	case *ast.ArrayType, *ast.ChanType, *ast.FuncType:
+		return false
+	}
+	return !isBasicType(typ)
+}
+
+// Returns true if the variant may be a struct held by value, whose methods with
+// pointer receivers, like the JSONEncode of a @struct, are only found through a
+// pointer to it.
+func (self *UnionFieldRepr) IsValueStruct() bool {
+	switch self.astType.(type) {
+	case *ast.StarExpr, *ast.MapType, *ast.InterfaceType:
+		return false
+	}
+	return maybeJsonObject(self.astType)
+}
+
+func (self *UnionRepr) HasValueStructs() bool {
+	for _, f := range self.Fields {
+		if f.IsValueStruct() {
+			return true
+		}
+	}
+	return false
+}
+
+// Reports whether the name is used by a method generated for every union.
+func (self *UnionRepr) isMethodName(name string) bool {
+	switch name {
+	case "Which", "IsZero", "Match", "Visit", "JSONEncode", "MarshalJSON",
+		"UnmarshalJSON":
+		return true
+	}
+	return false
+}
+
+func (self *FileData) GatherUnionImports() {
+	for _, repr := range self.Unions {
+		for _, f := range repr.Fields {
+			self.addTypeImports(f.astType)
+		}
+	}
+
+	for _, repr := range self.Unions {
+		if repr.DoJson() {
+			self.Imports["Golific/gJson"] = true
+			self.Imports["encoding/json"] = true
+			self.Imports["fmt"] = true
+			break
+		}
+	}
+}
+
+var union_tmpl = `
+{{- define "generate_union"}}
+{{- range $union := .}}
+{{- $kindField := .GetKindField}}
+{{- $valueField := .GetValueField}}
+{{- $kindType := printf "%sEnum" $union.Kind.Name}}
+
+/*****************************
+
+{{$union.Name}} union
+
+******************************/
+
+{{$union.DoDocs -}}
+type {{$union.Name}} struct {
+	{{$kindField}} {{$kindType}}
+	{{$valueField}} interface{}
+}
+
+{{range $f := .Fields -}}
+// New{{$union.Name}}{{$f.Name}} returns a {{$union.Name}} holding the {{$f.Name}} variant.
+func New{{$union.Name}}{{$f.Name}}(v {{$f.Type}}) {{$union.Name}} {
+	return {{$union.Name}}{
+		{{$kindField}}: {{$union.Kind.Name}}.{{$f.Name}},
+		{{$valueField}}: v,
+	}
+}
+
+{{end -}}
+
+// Which returns the kind of the variant held by the union. If no variant has
+// been set, the zero value of {{$kindType}} is returned.
+func (self {{$union.Name}}) Which() {{$kindType}} {
+	return self.{{$kindField}}
+}
+
+// IsZero returns true if no variant has been set.
+// This implements the Zeroable interface.
+func (self {{$union.Name}}) IsZero() bool {
+	return self.{{$kindField}} == {{$kindType}}{}
+}
+
+{{range $f := .Fields -}}
+// Is{{$f.Name}} returns 'true' if the union holds the {{$f.Name}} variant,
+// otherwise 'false'.
+func (self {{$union.Name}}) Is{{$f.Name}}() bool {
+	return self.{{$kindField}} == {{$union.Kind.Name}}.{{$f.Name}}
+}
+
+// As{{$f.Name}} returns the value of the {{$f.Name}} variant and 'true' if the
+// union holds it, otherwise the zero value and 'false'.
+func (self {{$union.Name}}) As{{$f.Name}}() ({{$f.Type}}, bool) {
+	if self.{{$kindField}} == {{$union.Kind.Name}}.{{$f.Name}} {
+		v, _ := self.{{$valueField}}.({{$f.Type}})
+		return v, true
+	}
+
+	var zero {{$f.Type}}
+	return zero, false
+}
+
+{{end -}}
+
+// Match calls the function given for the variant held by the union, passing it
+// the variant's value. There is one parameter per variant, so adding a variant
+// breaks every call site that doesn't handle it. No function is called if no
+// variant has been set.
+func (self {{$union.Name}}) Match(
+	{{- range $f := .Fields}}
+	on{{$f.Name}} func({{$f.Type}}),
+	{{- end}}
+) {
+	switch self.{{$kindField}} {
+	{{range $f := .Fields -}}
+	case {{$union.Kind.Name}}.{{$f.Name}}:
+		v, _ := self.{{$valueField}}.({{$f.Type}})
+		on{{$f.Name}}(v)
+	{{end -}}
+	}
+}
+
+// {{$union.Name}}Visitor has one method per variant of {{$union.Name}}. Adding a
+// variant breaks every implementation that doesn't handle it.
+type {{$union.Name}}Visitor interface {
+	{{- range $f := .Fields}}
+	Visit{{$f.Name}}({{$f.Type}})
+	{{- end}}
+}
+
+// Visit calls the method of 'v' for the variant held by the union, passing it
+// the variant's value. No method is called if no variant has been set.
+func (self {{$union.Name}}) Visit(v {{$union.Name}}Visitor) {
+	self.Match(
+		{{- range $f := .Fields}}
+		v.Visit{{$f.Name}},
+		{{- end}}
+	)
+}
+
+{{if $union.DoJson -}}
+// JSONEncode implements part of Golific's JSONEncodable interface.
+{{- if not (or $union.IsExternalJson $union.IsAdjacentJson)}}
+// A variant that isn't encoded as a JSON object is written as 'null', since
+// its keys can't be merged with the {{printf "%q" $union.JsonTag}} key. MarshalJSON returns an error
+// for it instead.
+{{- end}}
+func (self {{$union.Name}}) JSONEncode(encoder *gJson.Encoder) bool {
+	var pos = encoder.Len()
+
+	if err := self.encodeJSON(encoder); err != nil {
+		encoder.Truncate(pos)
+		return encoder.EncodeNull(false)
+	}
+	return true
+}
+
+// JSON marshaling methods
+func (self {{$union.Name}}) MarshalJSON() ([]byte, error) {
+	var encoder gJson.Encoder
+
+	if err := self.encodeJSON(&encoder); err != nil {
+		return nil, err
+	}
+	return encoder.Bytes(), nil
+}
+
+// encodeJSON writes the union to the encoder in the {{printf "%q" $union.GetJsonLayout}} layout.
+func (self {{$union.Name}}) encodeJSON(encoder *gJson.Encoder) error {
+	if self.IsZero() {
+		encoder.EncodeNull(false)
+		return nil
+	}
+
+	var value = self.{{$valueField}}
+	{{- if .HasValueStructs}}
+
+	// A copy is encoded through a pointer, so that its pointer methods are used
+	switch self.{{$kindField}} {
+	{{range $f := .Fields -}}
+	{{if $f.IsValueStruct -}}
+	case {{$union.Kind.Name}}.{{$f.Name}}:
+		if v, ok := value.({{$f.Type}}); ok {
+			value = &v
+		}
+	{{end -}}
+	{{end -}}
+	}
+	{{- end}}
+
+	encoder.WriteRawByte('{')
+	{{if $union.IsExternalJson -}}
+	encoder.EncodeKeyVal(self.{{$kindField}}.String(), value, true, false)
+	{{- else -}}
+	encoder.EncodeKeyVal({{printf "%q" $union.JsonTag}}, self.{{$kindField}}.String(), true, false)
+	{{- if $union.IsAdjacentJson}}
+	encoder.EncodeKeyVal({{printf "%q" $union.JsonValue}}, value, false, false)
+	{{- else}}
+
+	if _, err := encoder.EmbedObject(value, false); err != nil {
+		return fmt.Errorf("The %s variant of {{$union.Name}} can not be encoded "+
+			"with the {{$union.GetJsonLayout}} layout: %s", self.{{$kindField}}, err)
+	}
+	{{- end}}
+	{{- end}}
+	encoder.WriteRawByte('}')
+
+	return nil
+}
+
+func (self *{{$union.Name}}) UnmarshalJSON(b []byte) error {
+	var m map[string]json.RawMessage
+
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+
+	if m == nil { // was 'null'
+		*self = {{$union.Name}}{}
+		return nil
+	}
+
+	var kind string
+	var data json.RawMessage
+
+	{{if $union.IsExternalJson -}}
+	if len(m) != 1 {
+		return fmt.Errorf(
+			"Expected exactly one key while unmarshaling {{$union.Name}}; found %d", len(m),
+		)
+	}
+
+	for kind, data = range m {
+	}
+	{{- else -}}
+	if tag, ok := m[{{printf "%q" $union.JsonTag}}]; !ok {
+		return fmt.Errorf("Missing key %q while unmarshaling {{$union.Name}}", {{printf "%q" $union.JsonTag}})
+
+	} else if err := json.Unmarshal(tag, &kind); err != nil {
+		return err
+	}
+
+	{{if $union.IsAdjacentJson -}}
+	var ok bool
+	if data, ok = m[{{printf "%q" $union.JsonValue}}]; !ok {
+		return fmt.Errorf("Missing key %q while unmarshaling {{$union.Name}}", {{printf "%q" $union.JsonValue}})
+	}
+	{{- else -}}
+	data = json.RawMessage(b)
+	{{- end}}
+	{{- end}}
+
+	which, err := Parse{{$union.Kind.Name}}(kind)
+	if err != nil {
+		return err
+	}
+
+	switch which {
+	{{range $f := .Fields -}}
+	case {{$union.Kind.Name}}.{{$f.Name}}:
+		var v {{$f.Type}}
+		if err := json.Unmarshal(data, &v); err != nil {
+			return err
+		}
+		*self = New{{$union.Name}}{{$f.Name}}(v)
+	{{end -}}
+	}
+
+	return nil
+}
+{{end -}}
+{{end -}}
+{{end -}}
+`